@@ -0,0 +1,73 @@
+package fleetdm_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestConditionalRequestReusesBody(t *testing.T) {
+	telemetry := recordTelemetry(t)
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+	query := testQuery{Table: "fleetdm_pack", Columns: []string{"id", "name"}}
+
+	first := conn.rows(query)
+	second := conn.rows(query)
+
+	assertColumn(t, second, "id", columnValues(first, "id")...)
+	assertColumn(t, second, "name", columnValues(first, "name")...)
+	headers := fake.RequestHeaders("packs")
+	if len(headers) != 2 {
+		t.Fatalf("requests to packs = %d, want 2", len(headers))
+	}
+	if headers[0].Get("If-None-Match") != "" {
+		t.Errorf("first request sent If-None-Match %q", headers[0].Get("If-None-Match"))
+	}
+	if headers[1].Get("If-None-Match") == "" {
+		t.Errorf("second request did not send If-None-Match")
+	}
+	if got := telemetry.metricSum("fleetdm.api.requests", attribute.String("endpoint", "packs"), attribute.String("table", "fleetdm_pack"), attribute.Int("status_code", 304)); got != 1 {
+		t.Errorf("fleetdm.api.requests with status 304 = %d, want 1", got)
+	}
+}
+
+func TestConditionalRequestChangedResponse(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+	query := testQuery{Table: "fleetdm_software_version", Columns: []string{"id"}}
+
+	conn.rows(query)
+	conn.rows(query)
+	// The new counts_updated_at changes the body, so Fleet sends it in full
+	fake.SetCountsUpdatedAt("2024-06-01T13:00:00Z")
+	conn.rows(query)
+	rows := conn.rows(query)
+
+	assertColumn(t, rows, "id", 1, 2, 3)
+	headers := fake.RequestHeaders("software/versions")
+	if len(headers) != 4 {
+		t.Fatalf("requests to software/versions = %d, want 4", len(headers))
+	}
+	if before, after := headers[2].Get("If-None-Match"), headers[3].Get("If-None-Match"); before == after {
+		t.Errorf("If-None-Match %q was not replaced by the changed response's ETag", after)
+	}
+}
+
+func TestRequestsAcceptGzip(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	// The fake server gzips its responses whenever the client accepts it
+	rows := conn.rows(testQuery{Table: "fleetdm_host", Columns: []string{"id"}})
+
+	assertColumn(t, rows, "id", 1, 2, 3, 4, 5)
+	for _, header := range fake.RequestHeaders("hosts") {
+		if !strings.Contains(header.Get("Accept-Encoding"), "gzip") {
+			t.Errorf("Accept-Encoding = %q, want gzip", header.Get("Accept-Encoding"))
+		}
+	}
+}
@@ -2,24 +2,73 @@ package fleetdm
 
 import (
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
-	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/schema"
 )
 
 // fleetdmConfig contains the configuration for the FleetDM plugin.
 // These settings are defined in a .spc file, typically ~/.steampipe/config/fleetdm.spc
+//
+// The config is decoded from the hcl struct tags rather than a ConfigSchema, as the
+// legacy schema attributes cannot describe map values such as `headers`.
 type fleetdmConfig struct {
-	ServerURL *string `cty:"server_url"`
-	APIToken  *string `cty:"api_token"`
-}
+	ServerURL       *string `hcl:"server_url,optional"`
+	APIToken        *string `hcl:"api_token,optional"`
+	APITokenFile    *string `hcl:"api_token_file,optional"`    // File holding the token, re-read when the server answers 401
+	APITokenCommand *string `hcl:"api_token_command,optional"` // Command whose stdout is the token, re-run on 401
+	Email           *string `hcl:"email,optional"`             // Email/password log in through /login for a session token
+	Password        *string `hcl:"password,optional"`
+	MaxRetries      *int    `hcl:"max_retries,optional"`       // Retries after the first failed attempt for retryable errors
+	MaxRetryBackoff *int    `hcl:"max_retry_backoff,optional"` // Upper bound, in seconds, for a single backoff wait
+	PageSize        *int    `hcl:"page_size,optional"`         // per_page for every list endpoint, instead of each table's default
+	RequestTimeout  *int    `hcl:"request_timeout,optional"`   // Seconds a single request, including reading the response, may take
+
+	// Overrides of page_size and request_timeout keyed by table name, e.g.
+	// { fleetdm_software_version = 120 }
+	TablePageSize       map[string]int `hcl:"table_page_size,optional"`
+	TableRequestTimeout map[string]int `hcl:"table_request_timeout,optional"`
+
+	// Several Fleet servers queried together, each with a name, url and token,
+	// instead of server_url and the API token options. Rows carry their server's name.
+	Servers []fleetServer `hcl:"servers,optional"`
+
+	// HTTP connection pool settings shared by all tables of the connection
+	MaxIdleConnsPerHost *int `hcl:"max_idle_conns_per_host,optional"`
+	IdleConnTimeout     *int `hcl:"idle_conn_timeout,optional"` // Seconds an idle keep-alive connection stays in the pool
+	TCPKeepAlive        *int `hcl:"tcp_keep_alive,optional"`    // Seconds between TCP keep-alive probes
+
+	// TLS settings for self-hosted servers and mTLS reverse proxies
+	CACertFile         *string `hcl:"ca_cert_file,optional"`     // PEM bundle trusted in addition to the system roots
+	ClientCertFile     *string `hcl:"client_cert_file,optional"` // PEM client certificate, requires client_key_file
+	ClientKeyFile      *string `hcl:"client_key_file,optional"`
+	TLSServerName      *string `hcl:"tls_server_name,optional"` // Name used for SNI and certificate verification
+	InsecureSkipVerify *bool   `hcl:"insecure_skip_verify,optional"`
+
+	// Fronted deployments: an explicit egress proxy and extra headers sent with every request
+	ProxyURL *string           `hcl:"proxy_url,optional"`
+	Headers  map[string]string `hcl:"headers,optional"` // e.g. CF-Access-Client-Id / CF-Access-Client-Secret
+
+	// Offline reproduction: write every request and response to cassettes, or serve
+	// responses from previously recorded cassettes without any network access
+	RecordDir *string `hcl:"record_dir,optional"`
+	ReplayDir *string `hcl:"replay_dir,optional"`
+
+	// Per-connection request limits (requests per second) for each endpoint family
+	HostsRateLimit           *float64 `hcl:"hosts_rate_limit,optional"`
+	HostDetailRateLimit      *float64 `hcl:"host_detail_rate_limit,optional"`
+	HostDetailMaxConcurrency *int     `hcl:"host_detail_max_concurrency,optional"`
+	SoftwareRateLimit        *float64 `hcl:"software_rate_limit,optional"`
+	ActivitiesRateLimit      *float64 `hcl:"activities_rate_limit,optional"`
+
+	// On-disk response cache, kept across Steampipe sessions. A family is only
+	// cached when its TTL, in seconds, is set.
+	CacheDir           *string `hcl:"cache_dir,optional"`
+	HostsCacheTTL      *int    `hcl:"hosts_cache_ttl,optional"`
+	HostDetailCacheTTL *int    `hcl:"host_detail_cache_ttl,optional"`
+	SoftwareCacheTTL   *int    `hcl:"software_cache_ttl,optional"` // Also covers os_versions
+	ActivitiesCacheTTL *int    `hcl:"activities_cache_ttl,optional"`
 
-// ConfigSchema defines the schema for the plugin's connection configuration.
-var ConfigSchema = map[string]*schema.Attribute{
-	"server_url": {
-		Type: schema.TypeString,
-	},
-	"api_token": {
-		Type: schema.TypeString,
-	},
+	// Endpoints, e.g. "hosts/:id", or "*" for all, whose full response bodies are
+	// logged at debug level without redacting secrets
+	DebugLogBodies []string `hcl:"debug_log_bodies,optional"`
 }
 
 // ConfigInstance returns a new instance of the fleetdmConfig struct.
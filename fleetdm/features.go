@@ -0,0 +1,112 @@
+package fleetdm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleetapi"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"golang.org/x/sync/singleflight"
+)
+
+// serverInfoCacheKey prefixes the connection cache keys under which the detected
+// server info is stored, one per Fleet server.
+const serverInfoCacheKey = "fleetdm_server_info"
+
+// serverInfoTTL is how long detected server info is kept, so a Fleet upgrade or
+// license change is picked up without restarting the plugin.
+const serverInfoTTL = 5 * time.Minute
+
+// serverInfoGroup merges concurrent detection for the same connection and server,
+// so hydrates make a single pair of requests and a slow server holds up no other.
+var serverInfoGroup singleflight.Group
+
+// getServerInfo returns the version and license of the Fleet server the hydrate
+// runs for. Detection runs once per server and is cached for serverInfoTTL.
+func getServerInfo(ctx context.Context, d *plugin.QueryData) (*fleetapi.ServerInfo, error) {
+	cacheKey := serverInfoCacheKey + ":" + serverFromContext(ctx)
+	if cached, ok := d.ConnectionCache.Get(ctx, cacheKey); ok {
+		return cached.(*fleetapi.ServerInfo), nil
+	}
+
+	info, err, _ := serverInfoGroup.Do(d.Connection.Name+"/"+cacheKey, func() (interface{}, error) {
+		if cached, ok := d.ConnectionCache.Get(ctx, cacheKey); ok {
+			return cached.(*fleetapi.ServerInfo), nil
+		}
+
+		client, err := getClient(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := client.GetServerInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		plugin.Logger(ctx).Info("getServerInfo", "server", serverFromContext(ctx), "version", info.Version, "license_tier", info.LicenseTier)
+
+		if err := d.ConnectionCache.SetWithTTL(ctx, cacheKey, info, serverInfoTTL); err != nil {
+			plugin.Logger(ctx).Warn("getServerInfo", "connection_cache_set_error", err, "connection", d.Connection.Name)
+		}
+		return info, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info.(*fleetapi.ServerInfo), nil
+}
+
+// detectedServerInfo returns the server info for feature checks. Detection
+// failures are logged and reported as nil, so the checks let the query through
+// and the API decides, rather than failing queries that would otherwise work.
+func detectedServerInfo(ctx context.Context, d *plugin.QueryData) *fleetapi.ServerInfo {
+	info, err := getServerInfo(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Warn("detectedServerInfo", "server_detection_error", err)
+		return nil
+	}
+	return info
+}
+
+// checkPremiumQuals returns an error naming the first of the given quals used in
+// the query when the server runs the free tier, instead of an opaque API error.
+func checkPremiumQuals(ctx context.Context, d *plugin.QueryData, table string, quals ...string) error {
+	var used string
+	for _, qual := range quals {
+		if d.EqualsQuals[qual] != nil {
+			used = qual
+			break
+		}
+	}
+	if used == "" {
+		return nil
+	}
+	info := detectedServerInfo(ctx, d)
+	if info == nil || info.IsPremium() {
+		return nil
+	}
+	return fmt.Errorf("%s: the %s qual requires Fleet Premium, but the server reports the %s tier", table, used, info.LicenseTier)
+}
+
+// checkMinVersion returns an error when the server is older than the first
+// Fleet release serving the table's endpoint.
+func checkMinVersion(ctx context.Context, d *plugin.QueryData, table, minVersion string) error {
+	info := detectedServerInfo(ctx, d)
+	if info == nil || info.AtLeast(minVersion) {
+		return nil
+	}
+	return fmt.Errorf("%s requires Fleet %s or later, but the server runs %s", table, minVersion, info.Version)
+}
+
+// isFreeTier reports whether the server is known to run the free tier. Tables
+// backed by premium-only endpoints return no rows in that case.
+func isFreeTier(ctx context.Context, d *plugin.QueryData, table string) bool {
+	info := detectedServerInfo(ctx, d)
+	if info == nil || info.IsPremium() {
+		return false
+	}
+	plugin.Logger(ctx).Info(table, "premium_only_table_skipped", true, "license_tier", info.LicenseTier)
+	return true
+}
@@ -0,0 +1,69 @@
+package fleetapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+)
+
+// Activity represents an audit log activity in FleetDM.
+// Refer to: https://fleetdm.com/docs/rest-api/rest-api#activity-object
+type Activity struct {
+	ID              uint            `json:"id"`
+	CreatedAt       FleetTime       `json:"created_at"`
+	ActorFullName   string          `json:"actor_full_name"`
+	ActorID         *uint           `json:"actor_id"` // Can be null for system activities
+	ActorGravatar   string          `json:"actor_gravatar"`
+	Type            string          `json:"type"`                        // e.g., "created_user", "deleted_pack", "live_query"
+	Details         json.RawMessage `json:"details"`                     // JSON object, structure varies by type
+	ActorEmail      *string         `json:"actor_email,omitempty"`       // Not in main doc, but often present
+	ActorType       string          `json:"actor_type,omitempty"`        // e.g. "user", "system" - not in main doc but useful
+	HostID          *uint           `json:"host_id,omitempty"`           // If activity relates to a specific host
+	HostDisplayName *string         `json:"host_display_name,omitempty"` // If activity relates to a specific host
+}
+
+// ListActivitiesResponse for `GET /api/v1/fleet/activities`
+// The API returns {"activities": [...]}
+type ListActivitiesResponse struct {
+	Activities []Activity `json:"activities"`
+	Meta       struct {   // FleetDM API for activities includes a meta object for pagination
+		HasNextResults     bool   `json:"has_next_results"`
+		HasPreviousResults bool   `json:"has_previous_results"`
+		NextCursor         string `json:"next_cursor"` // Used as the `after` param when present, see paginateByCursor
+	} `json:"meta"`
+	Count int `json:"count"` // Total count of activities matching the query
+}
+
+// ListActivitiesOptions filters GET /api/v1/fleet/activities.
+type ListActivitiesOptions struct {
+	ListOptions
+	ActivityType   string // e.g. "created_user"
+	Query          string // Matches actor_full_name and actor_email
+	StartCreatedAt string // Only activities after this time, e.g. "2024-01-01T00:00:00Z"
+	EndCreatedAt   string // Only activities before this time
+}
+
+// ListActivities lists the audit log activities, oldest first, until fn returns false.
+func (c *FleetDMClient) ListActivities(ctx context.Context, opts ListActivitiesOptions, fn func(activity Activity) bool) error {
+	// The endpoint supports `after` for keyset pagination on the order key. Paging
+	// by id with `after` stays consistent while new activities are logged.
+	params := url.Values{}
+	params.Add("order_key", "id")
+	params.Add("order_direction", "asc") // Most recent (highest ID) last
+	setString(params, "activity_type", opts.ActivityType)
+	setString(params, "query", opts.Query)
+	setString(params, "start_created_at", opts.StartCreatedAt)
+	setString(params, "end_created_at", opts.EndCreatedAt)
+
+	pages := paginator[Activity]{
+		Name:     "FleetDMClient.ListActivities",
+		Endpoint: "activities",
+		Params:   params,
+		ItemsKey: "activities",
+		Mode:     paginateByCursor,
+		PageSize: 50, // API default is 20, max 100
+		Cursor:   func(activity Activity) string { return strconv.FormatUint(uint64(activity.ID), 10) },
+	}
+	return pages.walk(ctx, c, opts.ListOptions, fn)
+}
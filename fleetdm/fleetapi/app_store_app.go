@@ -0,0 +1,47 @@
+package fleetapi
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// AppStoreApp represents an Apple App Store app in FleetDM.
+// Refer to: https://fleetdm.com/docs/rest-api/rest-api#list-apple-app-store-apps
+type AppStoreApp struct {
+	AppStoreID       string      `json:"app_store_id"`
+	Platform         string      `json:"platform"`
+	SelfService      bool        `json:"self_service"`
+	LabelsIncludeAny interface{} `json:"labels_include_any"`
+	LabelsExcludeAny interface{} `json:"labels_exclude_any"`
+	CreatedAt        *FleetTime  `json:"created_at"`
+	Categories       interface{} `json:"categories"`
+	DisplayName      *string     `json:"display_name"`
+	BundleIdentifier string      `json:"bundle_identifier"`
+	IconURL          string      `json:"icon_url"`
+	Name             string      `json:"name"`
+	LatestVersion    string      `json:"latest_version"`
+}
+
+// ListAppStoreAppsResponse is the expected structure for the list app store apps API call.
+type ListAppStoreAppsResponse struct {
+	AppStoreApps []AppStoreApp `json:"app_store_apps"`
+}
+
+// ListAppStoreAppsOptions selects the team for GET /api/v1/fleet/software/app_store_apps.
+type ListAppStoreAppsOptions struct {
+	TeamID uint // Required by the endpoint
+}
+
+// ListAppStoreApps returns the App Store (VPP) apps available to a team. The
+// endpoint is not paginated. It requires Fleet Premium and Fleet 4.53.0 or later.
+func (c *FleetDMClient) ListAppStoreApps(ctx context.Context, opts ListAppStoreAppsOptions) ([]AppStoreApp, error) {
+	params := url.Values{}
+	params.Add("team_id", strconv.FormatUint(uint64(opts.TeamID), 10))
+
+	var response ListAppStoreAppsResponse
+	if _, err := c.Get(ctx, "software/app_store_apps", params, &response); err != nil {
+		return nil, err
+	}
+	return response.AppStoreApps, nil
+}
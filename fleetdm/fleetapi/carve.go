@@ -0,0 +1,50 @@
+package fleetapi
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+// Carve represents a file carving session in FleetDM.
+type Carve struct {
+	ID         uint      `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	HostID     uint      `json:"host_id"`
+	Name       string    `json:"name"`
+	BlockCount int64     `json:"block_count"`
+	BlockSize  int64     `json:"block_size"`
+	CarveSize  int64     `json:"carve_size"`
+	CarveID    string    `json:"carve_id"`
+	RequestID  string    `json:"request_id"`
+	SessionID  string    `json:"session_id"`
+	Expired    bool      `json:"expired"`
+	MaxBlock   int64     `json:"max_block"`
+	Error      *string   `json:"error,omitempty"` // Use pointer for optional field
+}
+
+// ListCarvesResponse is the structure for the list carves API response.
+type ListCarvesResponse struct {
+	Carves []Carve `json:"carves"`
+}
+
+// ListCarves lists the file carving sessions, most recent first and including
+// expired ones, until fn returns false.
+func (c *FleetDMClient) ListCarves(ctx context.Context, opts ListOptions, fn func(carve Carve) bool) error {
+	params := url.Values{}
+	params.Add("order_key", "id")
+	params.Add("order_direction", "desc") // Get most recent carves first
+	params.Add("expired", "true")         // Also get expired carves
+
+	// The /carves endpoint does not specify a meta object for pagination,
+	// so we rely on the number of items returned.
+	pages := paginator[Carve]{
+		Name:     "FleetDMClient.ListCarves",
+		Endpoint: "carves",
+		Params:   params,
+		ItemsKey: "carves",
+		Mode:     paginateByCount,
+		PageSize: 50,
+	}
+	return pages.walk(ctx, c, opts, fn)
+}
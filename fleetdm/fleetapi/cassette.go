@@ -0,0 +1,216 @@
+package fleetapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// A cassette is one recorded request and response, stored as a JSON file in
+// record_dir and served back from replay_dir. Cassettes are keyed by method, path
+// and query only, so they replay against any server_url. Credentials and secrets
+// are never written: request headers, sensitive query params and the string values
+// of sensitive keys in response bodies, such as enroll secrets and disk encryption
+// keys, are redacted, and request bodies are dropped.
+type cassette struct {
+	Request  cassetteRequest  `json:"request"`
+	Response cassetteResponse `json:"response"`
+}
+
+type cassetteRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"` // Path and redacted query, without the server
+	Headers map[string]string `json:"headers"`
+}
+
+type cassetteResponse struct {
+	StatusCode int                 `json:"status_code"`
+	Status     string              `json:"status"`
+	Headers    map[string][]string `json:"headers"`
+	Body       json.RawMessage     `json:"body,omitempty"`
+	// BodyText holds bodies which are not valid JSON, e.g. proxy error pages
+	BodyText string `json:"body_text,omitempty"`
+}
+
+// cassetteUnsafeChars matches characters not used in cassette file names.
+var cassetteUnsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// cassetteFileName returns the file a request is recorded to, e.g.
+// "GET_api_v1_fleet_hosts_1a2b3c4d.json". The hash covers the query, so each
+// page of a listing gets its own cassette.
+func cassetteFileName(req *http.Request) string {
+	key := req.Method + " " + cassetteRequestURL(req)
+	sum := sha256.Sum256([]byte(key))
+	path := strings.Trim(cassetteUnsafeChars.ReplaceAllString(req.URL.Path, "_"), "_")
+	if len(path) > 100 {
+		path = path[len(path)-100:]
+	}
+	return fmt.Sprintf("%s_%s_%s.json", req.Method, path, hex.EncodeToString(sum[:4]))
+}
+
+// cassetteRequestURL returns the request path and its query with sorted keys.
+func cassetteRequestURL(req *http.Request) string {
+	if query := req.URL.Query(); len(query) > 0 {
+		return req.URL.Path + "?" + query.Encode()
+	}
+	return req.URL.Path
+}
+
+// redactedRequestURL is cassetteRequestURL with sensitive query params masked,
+// for cassette contents and errors.
+func redactedRequestURL(req *http.Request) string {
+	if query := req.URL.Query(); len(query) > 0 {
+		return req.URL.Path + "?" + redactQuery(query)
+	}
+	return req.URL.Path
+}
+
+// recordingTransport passes requests through to the server and writes every
+// response to a cassette in dir. A retried request overwrites its cassette, so
+// the final attempt is what gets replayed.
+type recordingTransport struct {
+	next  http.RoundTripper
+	dir   string
+	mutex sync.Mutex
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := t.write(req, resp, body); err != nil {
+		return nil, fmt.Errorf("error recording cassette for %s %s: %w", req.Method, req.URL.Path, err)
+	}
+	return resp, nil
+}
+
+func (t *recordingTransport) write(req *http.Request, resp *http.Response, body []byte) error {
+	recorded := cassette{
+		Request: cassetteRequest{
+			Method:  req.Method,
+			URL:     redactedRequestURL(req),
+			Headers: redactHeaders(req.Header),
+		},
+		Response: cassetteResponse{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Headers:    make(map[string][]string),
+		},
+	}
+	for name, values := range resp.Header {
+		if name == "Set-Cookie" || name == "Content-Length" {
+			continue
+		}
+		recorded.Response.Headers[name] = values
+	}
+	if strings.HasSuffix(req.URL.Path, "/login") && resp.StatusCode < 300 {
+		// The session token must not end up in a bug report
+		body = []byte(`{"token":"<redacted>"}`)
+	}
+	body = redactJSONStrings(body)
+	if json.Valid(body) {
+		recorded.Response.Body = body
+	} else {
+		recorded.Response.BodyText = string(body)
+	}
+
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false) // Keep query strings and "<redacted>" readable
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(recorded); err != nil {
+		return err
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return os.WriteFile(filepath.Join(t.dir, cassetteFileName(req)), data.Bytes(), 0o600)
+}
+
+// replayTransport serves responses from the cassettes in dir and never touches
+// the network. A request without a cassette fails.
+type replayTransport struct {
+	dir string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	path := filepath.Join(t.dir, cassetteFileName(req))
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("replay_dir has no cassette for %s %s (expected %s)", req.Method, redactedRequestURL(req), filepath.Base(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var recorded cassette
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return nil, fmt.Errorf("error reading cassette %s: %w", path, err)
+	}
+	body := []byte(recorded.Response.Body)
+	if len(body) == 0 {
+		body = []byte(recorded.Response.BodyText)
+	}
+	return &http.Response{
+		StatusCode:    recorded.Response.StatusCode,
+		Status:        recorded.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header(recorded.Response.Headers),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// newCassetteTransport wraps next for the record_dir or replay_dir option. It
+// returns next unchanged when neither is set.
+func newCassetteTransport(config Config, next http.RoundTripper) (http.RoundTripper, error) {
+	recordDir, replayDir := "", ""
+	if config.RecordDir != nil {
+		recordDir = *config.RecordDir
+	}
+	if config.ReplayDir != nil {
+		replayDir = *config.ReplayDir
+	}
+
+	switch {
+	case recordDir != "" && replayDir != "":
+		return nil, errors.New("record_dir and replay_dir cannot both be set")
+	case recordDir != "":
+		if err := os.MkdirAll(recordDir, 0o700); err != nil {
+			return nil, fmt.Errorf("error creating record_dir '%s': %w", recordDir, err)
+		}
+		return &recordingTransport{next: next, dir: recordDir}, nil
+	case replayDir != "":
+		if info, err := os.Stat(replayDir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("replay_dir '%s' is not a readable directory", replayDir)
+		}
+		return &replayTransport{dir: replayDir}, nil
+	}
+	return next, nil
+}
@@ -0,0 +1,76 @@
+package fleetapi_test
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleetapi"
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestCassettesRedactSecrets(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	serverURL := fake.URL
+	token := fleettest.Token
+	dir := t.TempDir()
+	client, err := fleetapi.NewClient(context.Background(), fleetapi.Config{ServerURL: &serverURL, APIToken: &token, RecordDir: &dir})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if err := client.ListTeams(context.Background(), fleetapi.ListTeamsOptions{}, func(fleetapi.Team) bool { return true }); err != nil {
+		t.Fatalf("ListTeams: %v", err)
+	}
+	if _, err := client.Get(context.Background(), "hosts", url.Values{"enroll_secret": {"fleettest-query-secret"}}, nil); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no cassettes recorded in %s: %v", dir, err)
+	}
+	var teams, hosts string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range []string{"fleettest-enroll-secret", "fleettest-query-secret", fleettest.Token} {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("cassette %s contains %q", filepath.Base(file), secret)
+			}
+		}
+		switch {
+		case strings.Contains(file, "_teams_"):
+			teams = string(data)
+		case strings.Contains(file, "_hosts_"):
+			hosts = string(data)
+		}
+	}
+	if !strings.Contains(teams, `"secret": "<redacted>"`) || !strings.Contains(teams, `"created_at": "2024-01-05T00:00:00Z"`) {
+		t.Errorf("teams cassette does not mask only the secret values:\n%s", teams)
+	}
+	if !strings.Contains(hosts, "enroll_secret=<redacted>") {
+		t.Errorf("hosts cassette does not mask the enroll_secret param:\n%s", hosts)
+	}
+
+	// The masked secrets still decode when replayed
+	replay, err := fleetapi.NewClient(context.Background(), fleetapi.Config{ReplayDir: &dir})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	var secrets []fleetapi.TeamSecret
+	err = replay.ListTeams(context.Background(), fleetapi.ListTeamsOptions{}, func(team fleetapi.Team) bool {
+		secrets = append(secrets, team.Secrets...)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("replayed ListTeams: %v", err)
+	}
+	if len(secrets) == 0 || secrets[0].Secret != "<redacted>" || secrets[0].TeamID == 0 {
+		t.Errorf("replayed secrets = %+v", secrets)
+	}
+}
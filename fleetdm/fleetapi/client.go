@@ -0,0 +1,859 @@
+// Package fleetapi is a client for the Fleet REST API
+// (https://fleetdm.com/docs/rest-api/rest-api), with typed methods and response
+// types for the endpoints the Steampipe plugin queries. It retries transient
+// failures, refreshes rotated tokens and can rate limit, cache, record and replay
+// requests. It does not depend on the Steampipe SDK.
+package fleetapi
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"golang.org/x/time/rate"
+)
+
+// Endpoint families group the endpoints which share a rate limit and a cache TTL.
+// The names match the prefixes of the *_rate_limit and *_cache_ttl options.
+const (
+	EndpointFamilyHosts      = "hosts"
+	EndpointFamilyHostDetail = "host_detail"
+	EndpointFamilySoftware   = "software"
+	EndpointFamilyActivities = "activities"
+)
+
+// Retry defaults used when the connection config does not override them.
+const (
+	defaultMaxRetries      = 5
+	defaultMaxRetryBackoff = 30 * time.Second
+	minRetryBackoff        = 500 * time.Millisecond
+	// maxRetryDuration caps the total time a single Get call may spend retrying.
+	maxRetryDuration = 2 * time.Minute
+)
+
+// defaultRequestTimeout bounds a single request, including reading the response,
+// when the connection config does not override it.
+const defaultRequestTimeout = 30 * time.Second
+
+// Connection pool defaults. Go's default of 2 idle connections per host is far too
+// low for hydrates such as getHostDetails, which run concurrently once per host.
+const (
+	defaultMaxIdleConnsPerHost = 32
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultTCPKeepAlive        = 30 * time.Second
+)
+
+// replayServerURL is used as server_url when replaying cassettes without one configured.
+const replayServerURL = "http://fleet.replay.invalid"
+
+// FleetDMClient is a client for the FleetDM API.
+type FleetDMClient struct {
+	BaseURL         string
+	HTTPClient      *http.Client
+	MaxRetries      int
+	MaxRetryBackoff time.Duration
+	// Headers are added to every request, e.g. Cloudflare Access service tokens.
+	Headers http.Header
+
+	// The LabelRequestTimeout overrides, keyed by call label. The clients share
+	// HTTPClient's transport and connection pool.
+	labelHTTPClients map[string]*http.Client
+
+	// Optional client-side limits. In the plugin they apply on top of the
+	// plugin-level rate limiters.
+	rateLimiters    map[string]*rate.Limiter // keyed by endpoint family
+	hostDetailSlots chan struct{}            // nil when concurrency is not capped
+
+	// Optional on-disk response cache, nil unless cache_dir and a TTL are set
+	cache *responseCache
+	// Endpoint routes from debug_log_bodies whose bodies are logged unredacted
+	debugLogBodies map[string]bool
+	// Bodies kept for conditional requests, keyed by request URL
+	validators *validatorCache
+
+	// The API token and the source it came from, see credentials.go
+	credentials *credentialSource
+	tokenMutex  sync.RWMutex
+	apiToken    string
+
+	logger func(ctx context.Context) hclog.Logger
+}
+
+// NewClient creates a client for the server URL and API token of config. The
+// token is fetched, or the user logged in, before it returns.
+func NewClient(ctx context.Context, config Config) (*FleetDMClient, error) {
+	c := &FleetDMClient{logger: config.Logger}
+	serverURL := ""
+
+	// Get Server URL: .spc file takes precedence, then environment variable
+	if config.ServerURL != nil && *config.ServerURL != "" {
+		serverURL = *config.ServerURL
+		c.log(ctx).Info("fleetapi.NewClient", "server_url_source", ".spc_file")
+	} else {
+		envURL := os.Getenv("FLEETDM_URL")
+		if envURL != "" {
+			serverURL = envURL
+			c.log(ctx).Info("fleetapi.NewClient", "server_url_source", "env_FLEETDM_URL")
+		}
+	}
+
+	// Replaying cassettes never reaches a server, so neither a URL nor a token is needed
+	replaying := config.ReplayDir != nil && *config.ReplayDir != ""
+	if serverURL == "" && replaying {
+		serverURL = replayServerURL
+	}
+
+	// Validate that we have the necessary configuration
+	if serverURL == "" {
+		return nil, errors.New("server_url must be configured in fleetdm.spc or via FLEETDM_URL environment variable")
+	}
+
+	// Get API Token: one source from the .spc file, otherwise the environment variable
+	credentials, err := resolveCredentialSource(config)
+	if err != nil && replaying {
+		credentials = &credentialSource{
+			name:     "replay_dir",
+			identity: "replay",
+			fetch:    func(context.Context, *FleetDMClient) (string, error) { return "replay", nil },
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	c.log(ctx).Info("fleetapi.NewClient", "api_token_source", credentials.name)
+
+	// Normalize the baseURL
+	baseURL := strings.TrimSuffix(serverURL, "/")
+	if strings.HasSuffix(baseURL, "/api/v1/fleet") {
+		baseURL += "/"
+	} else if strings.HasSuffix(baseURL, "/api/v1") {
+		baseURL += "/fleet/"
+	} else if strings.HasSuffix(baseURL, "/api") {
+		baseURL += "/v1/fleet/"
+	} else {
+		baseURL += "/api/v1/fleet/"
+	}
+
+	c.log(ctx).Debug("fleetapi.NewClient", "final_derived_base_url", redactURL(baseURL))
+
+	maxRetries := defaultMaxRetries
+	if config.MaxRetries != nil {
+		if *config.MaxRetries < 0 {
+			return nil, errors.New("max_retries must be zero or greater")
+		}
+		maxRetries = *config.MaxRetries
+	}
+	maxRetryBackoff := defaultMaxRetryBackoff
+	if config.MaxRetryBackoff != nil {
+		if *config.MaxRetryBackoff < 1 {
+			return nil, errors.New("max_retry_backoff must be at least 1 second")
+		}
+		maxRetryBackoff = time.Duration(*config.MaxRetryBackoff) * time.Second
+	}
+
+	transport, err := newTransport(config)
+	if err != nil {
+		return nil, err
+	}
+	if transport.TLSClientConfig.InsecureSkipVerify {
+		c.log(ctx).Warn("fleetapi.NewClient", "insecure_skip_verify", true, "server_url", redactURL(serverURL))
+	}
+	roundTripper, err := newCassetteTransport(config, transport)
+	if err != nil {
+		return nil, err
+	}
+	if roundTripper != transport {
+		c.log(ctx).Info("fleetapi.NewClient", "record_dir", config.RecordDir != nil && *config.RecordDir != "", "replay_dir", replaying)
+	}
+
+	headers := http.Header{}
+	for name, value := range config.Headers {
+		if strings.TrimSpace(name) == "" {
+			return nil, errors.New("headers must not contain an empty header name")
+		}
+		headers.Set(name, value)
+	}
+	if len(headers) > 0 {
+		c.log(ctx).Info("fleetapi.NewClient", "custom_headers", redactHeaders(headers))
+	}
+
+	requestTimeout := defaultRequestTimeout
+	if config.RequestTimeout != nil {
+		if *config.RequestTimeout < 1 {
+			return nil, errors.New("request_timeout must be at least 1 second")
+		}
+		requestTimeout = time.Duration(*config.RequestTimeout) * time.Second
+	}
+	labelHTTPClients := make(map[string]*http.Client, len(config.LabelRequestTimeout))
+	for label, timeout := range config.LabelRequestTimeout {
+		if timeout < 1 {
+			return nil, fmt.Errorf("the request timeout for label %s must be at least 1 second", label)
+		}
+		labelHTTPClients[label] = &http.Client{
+			Timeout:   time.Duration(timeout) * time.Second,
+			Transport: roundTripper,
+		}
+	}
+
+	rateLimiters, err := newRateLimiters(config)
+	if err != nil {
+		return nil, err
+	}
+	var hostDetailSlots chan struct{}
+	if config.HostDetailMaxConcurrency != nil {
+		if *config.HostDetailMaxConcurrency < 1 {
+			return nil, errors.New("host_detail_max_concurrency must be at least 1")
+		}
+		hostDetailSlots = make(chan struct{}, *config.HostDetailMaxConcurrency)
+	}
+
+	cache, err := newResponseCache(config, credentials.identity)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		c.log(ctx).Info("fleetapi.NewClient", "cache_dir", cache.dir)
+	}
+
+	debugLogBodies, err := newDebugLogBodies(config)
+	if err != nil {
+		return nil, err
+	}
+	if len(debugLogBodies) > 0 {
+		c.log(ctx).Warn("fleetapi.NewClient", "debug_log_bodies", config.DebugLogBodies, "unredacted_response_bodies", true)
+	}
+
+	c.BaseURL = baseURL
+	c.HTTPClient = &http.Client{
+		Timeout:   requestTimeout,
+		Transport: roundTripper,
+	}
+	c.MaxRetries = maxRetries
+	c.MaxRetryBackoff = maxRetryBackoff
+	c.Headers = headers
+	c.labelHTTPClients = labelHTTPClients
+	c.rateLimiters = rateLimiters
+	c.hostDetailSlots = hostDetailSlots
+	c.cache = cache
+	c.debugLogBodies = debugLogBodies
+	c.validators = newValidatorCache(validatorCacheMaxBytes)
+	c.credentials = credentials
+
+	// Fetch the token last, as logging in needs the configured transport and headers
+	apiToken, err := credentials.fetch(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	c.apiToken = apiToken
+
+	return c, nil
+}
+
+// newRateLimiters builds the per-connection limiters for the endpoint families
+// which have a *_rate_limit option set. Families without one are not limited here.
+func newRateLimiters(config Config) (map[string]*rate.Limiter, error) {
+	limits := map[string]*float64{
+		EndpointFamilyHosts:      config.HostsRateLimit,
+		EndpointFamilyHostDetail: config.HostDetailRateLimit,
+		EndpointFamilySoftware:   config.SoftwareRateLimit,
+		EndpointFamilyActivities: config.ActivitiesRateLimit,
+	}
+	limiters := make(map[string]*rate.Limiter)
+	for family, limit := range limits {
+		if limit == nil {
+			continue
+		}
+		if *limit <= 0 {
+			return nil, fmt.Errorf("%s_rate_limit must be greater than zero", family)
+		}
+		// Allow a burst of one second's worth of requests
+		limiters[family] = rate.NewLimiter(rate.Limit(*limit), max(1, int(math.Ceil(*limit))))
+	}
+	return limiters, nil
+}
+
+// httpClient returns the client for requests made with the call label in ctx. It
+// only differs from HTTPClient when LabelRequestTimeout is set for the label.
+func (c *FleetDMClient) httpClient(ctx context.Context) *http.Client {
+	if client, ok := c.labelHTTPClients[labelFromContext(ctx)]; ok {
+		return client
+	}
+	return c.HTTPClient
+}
+
+// endpointFamily maps an API endpoint to the family used for rate limiting.
+// It returns an empty string for endpoints which are not rate limited.
+func endpointFamily(endpoint string) string {
+	endpoint = strings.Trim(endpoint, "/")
+	switch {
+	case endpoint == "hosts":
+		return EndpointFamilyHosts
+	case strings.HasPrefix(endpoint, "labels/") && strings.HasSuffix(endpoint, "/hosts"):
+		// A label's member hosts are listed like the hosts themselves
+		return EndpointFamilyHosts
+	case strings.HasPrefix(endpoint, "hosts/"):
+		return EndpointFamilyHostDetail
+	case endpoint == "software" || strings.HasPrefix(endpoint, "software/"):
+		return EndpointFamilySoftware
+	case endpoint == "activities":
+		return EndpointFamilyActivities
+	}
+	return ""
+}
+
+// newTransport builds the HTTP transport for a connection, applying the keep-alive,
+// connection pool, proxy and TLS settings from the connection config.
+func newTransport(config Config) (*http.Transport, error) {
+	maxIdleConnsPerHost := defaultMaxIdleConnsPerHost
+	if config.MaxIdleConnsPerHost != nil {
+		if *config.MaxIdleConnsPerHost < 1 {
+			return nil, errors.New("max_idle_conns_per_host must be at least 1")
+		}
+		maxIdleConnsPerHost = *config.MaxIdleConnsPerHost
+	}
+	idleConnTimeout := defaultIdleConnTimeout
+	if config.IdleConnTimeout != nil {
+		if *config.IdleConnTimeout < 0 {
+			return nil, errors.New("idle_conn_timeout must be zero or greater")
+		}
+		idleConnTimeout = time.Duration(*config.IdleConnTimeout) * time.Second
+	}
+	tcpKeepAlive := defaultTCPKeepAlive
+	if config.TCPKeepAlive != nil {
+		// A negative value disables TCP keep-alive probes, matching net.Dialer
+		tcpKeepAlive = time.Duration(*config.TCPKeepAlive) * time.Second
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: tcpKeepAlive,
+	}).DialContext
+	transport.MaxIdleConns = maxIdleConnsPerHost
+	transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	transport.IdleConnTimeout = idleConnTimeout
+
+	// An explicit proxy_url wins over the HTTPS_PROXY/HTTP_PROXY/NO_PROXY environment
+	// variables, which the default transport honors otherwise.
+	if config.ProxyURL != nil && *config.ProxyURL != "" {
+		proxyURL, err := url.Parse(*config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing proxy_url: %w", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("proxy_url scheme must be one of http, https, socks5 or socks5h, got '%s'", proxyURL.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// newTLSConfig builds the TLS settings for a connection: an optional CA bundle
+// trusted alongside the system roots, an optional client certificate for mTLS
+// proxies, a pinned server name and an explicit opt-out of verification.
+func newTLSConfig(config Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if config.CACertFile != nil && *config.CACertFile != "" {
+		pemBytes, err := os.ReadFile(*config.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading ca_cert_file '%s': %w", *config.CACertFile, err)
+		}
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil || rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(pemBytes) {
+			return nil, fmt.Errorf("ca_cert_file '%s' does not contain any PEM encoded certificates", *config.CACertFile)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	certFile := ""
+	if config.ClientCertFile != nil {
+		certFile = *config.ClientCertFile
+	}
+	keyFile := ""
+	if config.ClientKeyFile != nil {
+		keyFile = *config.ClientKeyFile
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("client_cert_file and client_key_file must be configured together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate '%s' and key '%s': %w", certFile, keyFile, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if config.TLSServerName != nil && *config.TLSServerName != "" {
+		tlsConfig.ServerName = *config.TLSServerName
+	}
+	if config.InsecureSkipVerify != nil && *config.InsecureSkipVerify {
+		tlsConfig.InsecureSkipVerify = true
+	}
+	return tlsConfig, nil
+}
+
+// Get performs a GET request to the specified FleetDM API endpoint.
+// The response is unmarshalled into the `target` interface.
+// Non-2xx responses are returned as a *FleetAPIError.
+func (c *FleetDMClient) Get(ctx context.Context, endpoint string, queryParams url.Values, target interface{}) (*http.Response, error) {
+	return c.do(ctx, endpoint, queryParams, func(resp *http.Response, requestURL string) error {
+		if target == nil {
+			return nil
+		}
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			c.log(ctx).Error("FleetDMClient.Get", "read_body_for_decode_error", err, "url", requestURL)
+			return fmt.Errorf("error reading response body from %s: %w", requestURL, err)
+		}
+
+		if err := json.Unmarshal(bodyBytes, target); err != nil {
+			c.log(ctx).Error("FleetDMClient.Get", "json_decode_error", err, "url", requestURL, "response_body_snippet", bodySnippet(bodyBytes)) // Log a snippet
+			return fmt.Errorf("error decoding JSON response from %s: %w. Response body: %s", requestURL, err, bodySnippet(bodyBytes))
+		}
+		return nil
+	})
+}
+
+// GetStream performs a GET request and decodes the response body item by item.
+// Each element of the top-level `itemsKey` array is handed to onItem, which must
+// consume exactly one value from the decoder, so only one item is held in memory
+// at a time however large the page is. The other top-level fields, such as `meta`
+// and `count`, are returned undecoded. An error returned by onItem stops decoding
+// and is returned unchanged.
+//
+// The body is spooled to a temporary file before the first item is decoded.
+// onItem may block, e.g. while Steampipe waits on row hydrates, and the request
+// timeout covers reading the body, so a slow consumer must not hold the response
+// open.
+func (c *FleetDMClient) GetStream(ctx context.Context, endpoint string, queryParams url.Values, itemsKey string, onItem func(dec *json.Decoder) error) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	_, err := c.do(ctx, endpoint, queryParams, func(resp *http.Response, requestURL string) error {
+		spooled, err := spoolBody(resp.Body)
+		if err != nil {
+			c.log(ctx).Error("FleetDMClient.GetStream", "read_body_error", err, "url", requestURL)
+			return fmt.Errorf("error reading response body from %s: %w", requestURL, err)
+		}
+		defer func() {
+			_ = spooled.Close()
+			_ = os.Remove(spooled.Name())
+		}()
+		fields, err = decodeItemStream(json.NewDecoder(spooled), itemsKey, onItem)
+		if err != nil && !errors.Is(err, errStopStream) {
+			c.log(ctx).Error("FleetDMClient.GetStream", "json_decode_error", err, "url", requestURL, "items_key", itemsKey)
+			return fmt.Errorf("error decoding JSON response from %s: %w", requestURL, err)
+		}
+		return err
+	})
+	return fields, err
+}
+
+// spoolBody copies a response body to a temporary file, readable only by the
+// current user, and returns the file rewound to its start. The caller closes and
+// removes it.
+func spoolBody(body io.Reader) (*os.File, error) {
+	file, err := os.CreateTemp("", "fleetdm-page-*.json")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(file, body); err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, err
+	}
+	return file, nil
+}
+
+// errStopStream may be returned by a GetStream onItem callback to stop decoding
+// the response early, e.g. once a query's limit is reached.
+var errStopStream = errors.New("stop reading response stream")
+
+// decodeItemStream walks a JSON object token by token, calling onItem for each
+// element of the itemsKey array and collecting every other field.
+func decodeItemStream(dec *json.Decoder, itemsKey string, onItem func(dec *json.Decoder) error) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return fields, err
+		}
+		key, _ := token.(string)
+		if key != itemsKey {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return fields, err
+			}
+			fields[key] = raw
+			continue
+		}
+
+		token, err = dec.Token()
+		if err != nil {
+			return fields, err
+		}
+		if token == nil {
+			continue // "items": null
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			return fields, fmt.Errorf("expected %q to be an array, found %v", itemsKey, token)
+		}
+		for dec.More() {
+			if err := onItem(dec); err != nil {
+				return fields, err
+			}
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return fields, err
+		}
+	}
+	return fields, expectDelim(dec, '}')
+}
+
+// expectDelim reads the next token and checks it is the given delimiter.
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %v, found %v", want, token)
+	}
+	return nil
+}
+
+// do performs a GET request to a FleetDM API endpoint, refreshing the token once on
+// a 401. Non-2xx responses are returned as a *FleetAPIError; for 2xx responses
+// read is called with the open body, which is closed once read returns.
+func (c *FleetDMClient) do(ctx context.Context, endpoint string, queryParams url.Values, read func(resp *http.Response, requestURL string) error) (resp *http.Response, err error) {
+	ctx, call := startAPICall(ctx, endpoint, queryParams)
+	defer func() { call.end(ctx, resp, err) }()
+
+	// Construct the full URL
+	// Ensure endpoint doesn't start with a slash if BaseURL already ends with one
+	trimmedEndpoint := strings.TrimPrefix(endpoint, "/")
+	fullURLString := c.BaseURL + trimmedEndpoint
+
+	fullURL, err := url.Parse(fullURLString)
+	if err != nil {
+		c.log(ctx).Error("FleetDMClient.Get", "url_parse_error", err, "base_url", redactURL(c.BaseURL), "endpoint", endpoint)
+		return nil, fmt.Errorf("error parsing base URL '%s' and endpoint '%s': %w", redactURL(c.BaseURL), endpoint, err)
+	}
+	if queryParams != nil {
+		fullURL.RawQuery = queryParams.Encode()
+	}
+
+	// Logs and errors only ever see the URL with sensitive query params masked
+	logURL := redactURL(fullURL.String())
+	c.log(ctx).Debug("FleetDMClient.Get", "url", logURL)
+
+	// Serve from the on-disk response cache when the endpoint's family has a TTL
+	mode := cacheModeFrom(ctx)
+	ttl := c.cache.ttl(endpoint)
+	if mode == cacheOff {
+		ttl = 0
+	}
+	if ttl > 0 && mode != cacheRefresh {
+		if body, ok := c.cachedResponse(ctx, endpoint, fullURL.String(), ttl); ok {
+			call.cacheHit = true
+			resp = bodyResponse(body, nil)
+			return resp, read(resp, logURL)
+		}
+	}
+
+	family := endpointFamily(endpoint)
+	if family == EndpointFamilyHostDetail && c.hostDetailSlots != nil {
+		select {
+		case c.hostDetailSlots <- struct{}{}:
+			defer func() { <-c.hostDetailSlots }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// Revalidate the body kept from the last identical request, if any
+	conditional := http.Header{}
+	validated := c.validators.get(fullURL.String())
+	if validated != nil {
+		validated.setConditionalHeaders(conditional)
+	}
+
+	// Perform the request, retrying transient failures
+	usedToken := c.token()
+	resp, err = c.doWithRetry(ctx, family, fullURL.String(), conditional)
+	if err != nil {
+		return resp, err
+	}
+
+	// A 401 may mean a rotated token or an expired login session. Refresh the
+	// token once from its source and repeat the request.
+	if resp.StatusCode == http.StatusUnauthorized {
+		refreshed, refreshErr := c.refreshToken(ctx, usedToken)
+		if refreshErr != nil {
+			_ = resp.Body.Close()
+			c.log(ctx).Error("FleetDMClient.Get", "token_refresh_error", refreshErr, "url", logURL)
+			return nil, refreshErr
+		}
+		if refreshed {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			resp, err = c.doWithRetry(ctx, family, fullURL.String(), conditional)
+			if err != nil {
+				return resp, err
+			}
+		}
+	}
+	if resp.StatusCode == http.StatusNotModified && validated != nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		c.log(ctx).Debug("FleetDMClient.Get", "not_modified", true, "url", logURL)
+		call.notModified = true
+		resp = bodyResponse(validated.body, resp.Header)
+	}
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil {
+			c.log(ctx).Error("FleetDMClient.Get", "close_error", cerr, "url", logURL)
+		}
+	}()
+
+	// Check for non-2xx status codes
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		bodyBytes, readErr := io.ReadAll(resp.Body)
+		if readErr != nil {
+			c.log(ctx).Error("FleetDMClient.Get", "read_error_body_failed", readErr, "url", logURL, "status_code", resp.StatusCode)
+			return resp, fmt.Errorf("API request to %s failed with status %s (unable to read error body)", logURL, resp.Status)
+		}
+		apiErr := newFleetAPIError(resp, fullURL.String(), bodyBytes)
+		c.log(ctx).Error("FleetDMClient.Get", "api_error_response", bodySnippet(bodyBytes), "url", logURL, "status_code", resp.StatusCode, "request_id", apiErr.RequestID)
+		if c.logsBodies(endpoint) {
+			c.log(ctx).Debug("FleetDMClient.Get", "response_body", string(bodyBytes), "url", logURL)
+		}
+		return resp, apiErr
+	}
+
+	logBody := c.logsBodies(endpoint)
+	revalidate := !call.notModified && hasValidators(resp.Header)
+	if ttl == 0 && !logBody && !revalidate {
+		return resp, read(resp, logURL)
+	}
+
+	// Keep a copy of the body as it is read, to cache it or keep it for conditional
+	// requests once the whole response was decoded, and to log it for
+	// debug_log_bodies. A response cut short by a query's limit is still complete
+	// on the wire, so the rest is read first.
+	body := newCachingBody(resp.Body)
+	resp.Body = body
+	err = read(resp, logURL)
+	data, readErr := body.finish()
+	if logBody && readErr == nil {
+		c.log(ctx).Debug("FleetDMClient.Get", "response_body", string(data), "url", logURL)
+	}
+	if readErr != nil || (err != nil && !errors.Is(err, errStopStream)) {
+		return resp, err
+	}
+	if revalidate {
+		c.validators.put(fullURL.String(), resp.Header, data)
+	}
+	if ttl > 0 {
+		if storeErr := c.cache.store(endpoint, fullURL.String(), data); storeErr != nil {
+			c.log(ctx).Warn("FleetDMClient.Get", "cache_store_error", storeErr, "url", logURL)
+		}
+	}
+	return resp, err
+}
+
+// doWithRetry performs a GET request against fullURL, retrying rate limited (429),
+// gateway (502, 503, 504) and transient network failures with exponential backoff
+// and jitter. Each attempt first waits on the connection's limiter for the
+// endpoint family, if one is configured. A Retry-After header sent by the server
+// takes precedence over the computed backoff. Retrying stops after c.MaxRetries retries or once the total
+// time spent would exceed maxRetryDuration. Any other response, including
+// non-retryable 4xx errors, is returned to the caller immediately. header is
+// added to every attempt, e.g. the conditional request headers.
+func (c *FleetDMClient) doWithRetry(ctx context.Context, family string, fullURL string, header http.Header) (*http.Response, error) {
+	start := time.Now()
+	logURL := redactURL(fullURL)
+
+	for attempt := 0; ; attempt++ {
+		// Every attempt, including retries, counts against the connection's limit
+		if limiter := c.rateLimiters[family]; limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		// Create the request
+		req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
+		if err != nil {
+			c.log(ctx).Error("FleetDMClient.Get", "request_creation_error", err, "url", logURL)
+			return nil, fmt.Errorf("error creating HTTP request for %s: %w", logURL, err)
+		}
+
+		// Set headers. Custom headers go first so they cannot replace the API token.
+		for name, values := range c.Headers {
+			req.Header[name] = values
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token())
+		// The transport adds Accept-Encoding: gzip and decompresses the response
+		req.Header.Set("Accept", "application/json")
+		c.log(ctx).Trace("FleetDMClient.Get", "url", logURL, "headers", redactHeaders(req.Header))
+
+		resp, err := c.httpClient(ctx).Do(req)
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = logURL
+		}
+
+		var wait time.Duration
+		switch {
+		case err != nil:
+			if !isRetryableError(ctx, err) {
+				c.log(ctx).Error("FleetDMClient.Get", "http_do_error", err, "url", logURL)
+				return resp, fmt.Errorf("error performing HTTP request to %s: %w", logURL, err)
+			}
+			wait = retryBackoff(attempt, c.MaxRetryBackoff)
+		case isRetryableStatus(resp.StatusCode):
+			wait = retryAfter(resp)
+			if wait == 0 {
+				wait = retryBackoff(attempt, c.MaxRetryBackoff)
+			}
+		default:
+			return resp, nil
+		}
+
+		// Give up once the retry budget is spent. For a retryable status the last
+		// response is handed back so the caller reports the API error as usual.
+		if attempt >= c.MaxRetries || time.Since(start)+wait > maxRetryDuration {
+			if err != nil {
+				c.log(ctx).Error("FleetDMClient.Get", "http_do_error", err, "url", logURL, "attempts", attempt+1)
+				return resp, fmt.Errorf("error performing HTTP request to %s after %d attempts: %w", logURL, attempt+1, err)
+			}
+			c.log(ctx).Warn("FleetDMClient.Get", "retries_exhausted", true, "url", logURL, "status_code", resp.StatusCode, "attempts", attempt+1)
+			return resp, nil
+		}
+
+		if resp != nil {
+			// Drain the body so the connection can be reused for the next attempt
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			c.log(ctx).Warn("FleetDMClient.Get", "retrying_status", resp.StatusCode, "url", logURL, "attempt", attempt+1, "wait", wait.String())
+		} else {
+			c.log(ctx).Warn("FleetDMClient.Get", "retrying_error", err, "url", logURL, "attempt", attempt+1, "wait", wait.String())
+		}
+
+		recordRetry(ctx)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// isRetryableStatus reports whether an HTTP status code indicates a transient server-side condition.
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isRetryableError reports whether a transport error is transient, e.g. a connection
+// reset or a timeout. Errors caused by the query context being cancelled are never retried.
+func isRetryableError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryBackoff returns an exponential backoff for the given (zero-based) attempt,
+// capped at maxBackoff, with "equal jitter" applied so concurrent hydrates spread out.
+func retryBackoff(attempt int, maxBackoff time.Duration) time.Duration {
+	backoff := maxBackoff
+	if attempt < 16 { // avoid overflowing the shift
+		if b := minRetryBackoff << attempt; b < maxBackoff {
+			backoff = b
+		}
+	}
+	half := backoff / 2
+	return half + rand.N(half+1)
+}
+
+// retryAfter parses the Retry-After header, which is either a number of seconds or
+// an HTTP date. It returns zero when the header is absent or cannot be parsed.
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// redactHeaders returns the request headers with their values masked, so header
+// names can be logged without leaking tokens or service credentials.
+func redactHeaders(headers http.Header) map[string]string {
+	redacted := make(map[string]string, len(headers))
+	for name := range headers {
+		if name == "Accept" {
+			redacted[name] = headers.Get(name)
+			continue
+		}
+		redacted[name] = "<redacted>"
+	}
+	return redacted
+}
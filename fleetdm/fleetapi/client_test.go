@@ -0,0 +1,155 @@
+package fleetapi_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleetapi"
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func newTestClient(t *testing.T, fake *fleettest.Server) *fleetapi.FleetDMClient {
+	t.Helper()
+	serverURL := fake.URL
+	token := fleettest.Token
+	client, err := fleetapi.NewClient(context.Background(), fleetapi.Config{ServerURL: &serverURL, APIToken: &token})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return client
+}
+
+func TestClientListHosts(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	client := newTestClient(t, fake)
+
+	teamID := uint(1)
+	opts := fleetapi.ListHostsOptions{
+		ListOptions: fleetapi.ListOptions{PerPage: 1},
+		TeamID:      &teamID,
+		Populate:    []string{"populate_policies"},
+	}
+	var ids []int
+	err := client.ListHosts(context.Background(), opts, func(host fleetapi.Host) bool {
+		ids = append(ids, host.ID)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("ListHosts: %v", err)
+	}
+	if len(ids) == 0 {
+		t.Fatal("ListHosts returned no hosts for team 1")
+	}
+
+	requests := fake.Requests("hosts")
+	if len(requests) != len(ids)+1 {
+		t.Errorf("requests to hosts = %d, want one per host and a final empty page: %v", len(requests), requests)
+	}
+	for _, params := range requests {
+		if params.Get("team_id") != "1" || params.Get("populate_policies") != "true" || params.Get("per_page") != "1" {
+			t.Errorf("request params = %v", params)
+		}
+		if params.Has("status") || params.Has("low_disk_space") {
+			t.Errorf("unset filters were sent: %v", params)
+		}
+	}
+}
+
+func TestClientListHostsLimit(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	client := newTestClient(t, fake)
+
+	var ids []int
+	opts := fleetapi.ListHostsOptions{ListOptions: fleetapi.ListOptions{Limit: 2}, OrderDirection: "asc"}
+	err := client.ListHosts(context.Background(), opts, func(host fleetapi.Host) bool {
+		ids = append(ids, host.ID)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("ListHosts: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("ids = %v, want [1 2]", ids)
+	}
+	requests := fake.Requests("hosts")
+	if len(requests) != 1 || requests[0].Get("per_page") != "2" {
+		t.Errorf("requests to hosts = %v, want a single page of 2", requests)
+	}
+}
+
+func TestClientGetHost(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	client := newTestClient(t, fake)
+
+	host, err := client.GetHost(context.Background(), 1, fleetapi.GetHostOptions{})
+	if err != nil {
+		t.Fatalf("GetHost: %v", err)
+	}
+	if host.ID != 1 || host.Hostname != "alice-mbp.local" {
+		t.Errorf("host = %d %q, want 1 alice-mbp.local", host.ID, host.Hostname)
+	}
+
+	_, err = client.GetHost(context.Background(), 999, fleetapi.GetHostOptions{})
+	var apiErr *fleetapi.FleetAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 404 {
+		t.Errorf("GetHost(999) error = %v, want a 404 FleetAPIError", err)
+	}
+}
+
+func TestClientGetServerInfo(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{Tier: fleettest.TierFree, Version: "4.60.0"})
+	client := newTestClient(t, fake)
+
+	info, err := client.GetServerInfo(context.Background())
+	if err != nil {
+		t.Fatalf("GetServerInfo: %v", err)
+	}
+	if info.IsPremium() || info.Version != "4.60.0" || info.ServerURL != fake.URL {
+		t.Errorf("info = %+v", info)
+	}
+	if !info.AtLeast("4.59.2") || info.AtLeast("4.61.0") {
+		t.Errorf("AtLeast is wrong for version %s", info.Version)
+	}
+}
+
+func TestClientListHostsSlowConsumer(t *testing.T) {
+	// The second host arrives after the first was handed out, so decoding it needs
+	// another read of the body
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "0" {
+			_, _ = w.Write([]byte(`{"hosts":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"hosts":[{"id":1}`))
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte(`,{"id":2}]}`))
+	}))
+	t.Cleanup(server.Close)
+	token := fleettest.Token
+	timeout := 1
+	client, err := fleetapi.NewClient(context.Background(), fleetapi.Config{ServerURL: &server.URL, APIToken: &token, RequestTimeout: &timeout})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	// Rows waiting on slow hydrates must not hold the response open past the request timeout
+	var ids []int
+	err = client.ListHosts(context.Background(), fleetapi.ListHostsOptions{}, func(host fleetapi.Host) bool {
+		if len(ids) == 0 {
+			time.Sleep(1500 * time.Millisecond)
+		}
+		ids = append(ids, host.ID)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("ListHosts: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Errorf("listed hosts %v, want [1 2]", ids)
+	}
+}
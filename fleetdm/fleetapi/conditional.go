@@ -0,0 +1,125 @@
+package fleetapi
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// validatorCacheMaxBytes caps the response bodies a connection keeps in memory for
+// conditional requests. The least recently used bodies are dropped first.
+const validatorCacheMaxBytes = 64 << 20
+
+// validatedResponse is a response body with the validators the server sent for it.
+type validatedResponse struct {
+	url          string
+	etag         string
+	lastModified string
+	body         []byte
+}
+
+// setConditionalHeaders asks the server to answer 304 Not Modified if the
+// response has not changed since it was validated.
+func (v *validatedResponse) setConditionalHeaders(header http.Header) {
+	if v.etag != "" {
+		header.Set("If-None-Match", v.etag)
+	}
+	if v.lastModified != "" {
+		header.Set("If-Modified-Since", v.lastModified)
+	}
+}
+
+// validatorCache keeps the last ETag and Last-Modified seen for each request URL,
+// so endpoint and params, together with the body they validate. Repeat scans then
+// send conditional requests, and a 304 reuses the kept body instead of
+// transferring the page again. Unlike the on-disk response cache, it never serves
+// a response the server has not confirmed, so it is always enabled.
+type validatorCache struct {
+	mutex    sync.Mutex
+	maxBytes int
+	size     int
+	entries  map[string]*list.Element
+	order    *list.List // Of *validatedResponse, most recently used first
+}
+
+func newValidatorCache(maxBytes int) *validatorCache {
+	return &validatorCache{
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get returns the validated response for requestURL, if one is kept.
+func (vc *validatorCache) get(requestURL string) *validatedResponse {
+	vc.mutex.Lock()
+	defer vc.mutex.Unlock()
+	element, ok := vc.entries[requestURL]
+	if !ok {
+		return nil
+	}
+	vc.order.MoveToFront(element)
+	return element.Value.(*validatedResponse)
+}
+
+// put keeps body for requestURL if the response headers carry a validator.
+func (vc *validatorCache) put(requestURL string, header http.Header, body []byte) {
+	v := &validatedResponse{
+		url:          requestURL,
+		etag:         header.Get("ETag"),
+		lastModified: header.Get("Last-Modified"),
+		body:         body,
+	}
+	if (v.etag == "" && v.lastModified == "") || len(body) > vc.maxBytes {
+		vc.remove(requestURL)
+		return
+	}
+
+	vc.mutex.Lock()
+	defer vc.mutex.Unlock()
+	if element, ok := vc.entries[requestURL]; ok {
+		vc.size -= len(element.Value.(*validatedResponse).body)
+		vc.order.Remove(element)
+	}
+	vc.entries[requestURL] = vc.order.PushFront(v)
+	vc.size += len(body)
+	for vc.size > vc.maxBytes {
+		oldest := vc.order.Back()
+		evicted := vc.order.Remove(oldest).(*validatedResponse)
+		delete(vc.entries, evicted.url)
+		vc.size -= len(evicted.body)
+	}
+}
+
+func (vc *validatorCache) remove(requestURL string) {
+	vc.mutex.Lock()
+	defer vc.mutex.Unlock()
+	if element, ok := vc.entries[requestURL]; ok {
+		vc.size -= len(element.Value.(*validatedResponse).body)
+		vc.order.Remove(element)
+		delete(vc.entries, requestURL)
+	}
+}
+
+// hasValidators reports whether a response can be revalidated later.
+func hasValidators(header http.Header) bool {
+	return header.Get("ETag") != "" || header.Get("Last-Modified") != ""
+}
+
+// bodyResponse returns a 200 response with body, for responses served from the
+// response cache or revalidated with a 304.
+func bodyResponse(body []byte, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Content-Type", "application/json")
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Status:        "200 OK",
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
@@ -0,0 +1,80 @@
+package fleetapi
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// Config holds the options of a FleetDMClient. The fields mirror the fleetdm.spc
+// connection options of the same name: nil and empty fields take the same
+// defaults, and errors about invalid values name the options that way.
+type Config struct {
+	ServerURL       *string // Falls back to the FLEETDM_URL environment variable
+	APIToken        *string // At most one of the token sources may be set, FLEETDM_API_TOKEN is used otherwise
+	APITokenFile    *string // File holding the token, re-read when the server answers 401
+	APITokenCommand *string // Command whose stdout is the token, re-run on 401
+	Email           *string // Email/password log in through /login for a session token
+	Password        *string
+	MaxRetries      *int // Retries after the first failed attempt for retryable errors
+	MaxRetryBackoff *int // Upper bound, in seconds, for a single backoff wait
+	RequestTimeout  *int // Seconds a single request, including reading the response, may take
+
+	// Overrides of RequestTimeout keyed by the call label set with WithLabel. The
+	// plugin's table_request_timeout option labels calls by table name.
+	LabelRequestTimeout map[string]int
+
+	// HTTP connection pool settings
+	MaxIdleConnsPerHost *int
+	IdleConnTimeout     *int // Seconds an idle keep-alive connection stays in the pool
+	TCPKeepAlive        *int // Seconds between TCP keep-alive probes
+
+	// TLS settings for self-hosted servers and mTLS reverse proxies
+	CACertFile         *string // PEM bundle trusted in addition to the system roots
+	ClientCertFile     *string // PEM client certificate, requires ClientKeyFile
+	ClientKeyFile      *string
+	TLSServerName      *string // Name used for SNI and certificate verification
+	InsecureSkipVerify *bool
+
+	// Fronted deployments: an explicit egress proxy and extra headers sent with every request
+	ProxyURL *string
+	Headers  map[string]string
+
+	// Write every request and response to cassettes, or serve responses from
+	// previously recorded cassettes without any network access
+	RecordDir *string
+	ReplayDir *string
+
+	// Client-side request limits (requests per second) for each endpoint family
+	HostsRateLimit           *float64
+	HostDetailRateLimit      *float64
+	HostDetailMaxConcurrency *int
+	SoftwareRateLimit        *float64
+	ActivitiesRateLimit      *float64
+
+	// On-disk response cache. A family is only cached when its TTL, in seconds, is set.
+	CacheDir           *string
+	HostsCacheTTL      *int
+	HostDetailCacheTTL *int
+	SoftwareCacheTTL   *int // Also covers os_versions
+	ActivitiesCacheTTL *int
+
+	// Endpoints, e.g. "hosts/:id", or "*" for all, whose full response bodies are
+	// logged at debug level without redacting secrets
+	DebugLogBodies []string
+
+	// Logger returns the logger for a call's context, e.g. plugin.Logger in a
+	// Steampipe plugin. Nothing is logged when it is nil.
+	Logger func(ctx context.Context) hclog.Logger
+}
+
+// nullLogger is used when Config.Logger is not set.
+var nullLogger = hclog.NewNullLogger()
+
+// log returns the logger for ctx.
+func (c *FleetDMClient) log(ctx context.Context) hclog.Logger {
+	if c.logger == nil {
+		return nullLogger
+	}
+	return c.logger(ctx)
+}
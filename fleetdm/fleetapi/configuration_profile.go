@@ -0,0 +1,74 @@
+package fleetapi
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// ConfigurationProfileLabel is a label a configuration profile is scoped to.
+type ConfigurationProfileLabel struct {
+	ID     uint   `json:"id"`
+	Name   string `json:"name"`
+	Broken bool   `json:"broken,omitempty"` // The label was deleted after the profile was scoped to it
+}
+
+// ConfigurationProfile is an Apple or Windows MDM configuration profile.
+// Refer to: https://fleetdm.com/docs/rest-api/rest-api#list-custom-os-settings-configuration-profiles
+type ConfigurationProfile struct {
+	ProfileUUID      string                      `json:"profile_uuid"`
+	TeamID           uint                        `json:"team_id"` // 0 for profiles of hosts without a team
+	Name             string                      `json:"name"`
+	Platform         string                      `json:"platform"`   // "darwin" or "windows"
+	Identifier       string                      `json:"identifier"` // PayloadIdentifier, Apple profiles only
+	Checksum         string                      `json:"checksum"`   // Base64 MD5 of the profile contents
+	CreatedAt        FleetTime                   `json:"created_at"`
+	UploadedAt       FleetTime                   `json:"uploaded_at"`
+	LabelsIncludeAll []ConfigurationProfileLabel `json:"labels_include_all,omitempty"`
+	LabelsIncludeAny []ConfigurationProfileLabel `json:"labels_include_any,omitempty"`
+	LabelsExcludeAny []ConfigurationProfileLabel `json:"labels_exclude_any,omitempty"`
+}
+
+// ConfigurationProfileStatus counts the hosts in each delivery status of a
+// configuration profile.
+type ConfigurationProfileStatus struct {
+	Verified  uint `json:"verified"`
+	Verifying uint `json:"verifying"`
+	Pending   uint `json:"pending"`
+	Failed    uint `json:"failed"`
+}
+
+// ListConfigurationProfilesOptions selects the team of GET /api/v1/fleet/configuration_profiles.
+type ListConfigurationProfilesOptions struct {
+	ListOptions
+	TeamID uint // 0 lists the profiles of hosts without a team
+}
+
+// ListConfigurationProfiles lists the configuration profiles of a team until fn
+// returns false.
+func (c *FleetDMClient) ListConfigurationProfiles(ctx context.Context, opts ListConfigurationProfilesOptions, fn func(profile ConfigurationProfile) bool) error {
+	params := url.Values{}
+	if opts.TeamID != 0 {
+		params.Add("team_id", strconv.FormatUint(uint64(opts.TeamID), 10))
+	}
+
+	pages := paginator[ConfigurationProfile]{
+		Name:     "FleetDMClient.ListConfigurationProfiles",
+		Endpoint: "configuration_profiles",
+		Params:   params,
+		ItemsKey: "profiles",
+		Mode:     paginateByMeta,
+		PageSize: 100,
+	}
+	return pages.walk(ctx, c, opts.ListOptions, fn)
+}
+
+// GetConfigurationProfileStatus returns the host counts for each delivery status
+// of the configuration profile with the given UUID.
+func (c *FleetDMClient) GetConfigurationProfileStatus(ctx context.Context, profileUUID string) (*ConfigurationProfileStatus, error) {
+	var status ConfigurationProfileStatus
+	if _, err := c.Get(ctx, "configuration_profiles/"+url.PathEscape(profileUUID)+"/status", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
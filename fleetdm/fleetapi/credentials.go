@@ -0,0 +1,224 @@
+package fleetapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// apiTokenCommandTimeout bounds how long api_token_command may run.
+const apiTokenCommandTimeout = 30 * time.Second
+
+// credentialSource describes where the client gets its API token from.
+type credentialSource struct {
+	// name identifies the source in the logs, e.g. "spc_api_token_file"
+	name string
+	// identity tells sources apart which may see different data, such as two
+	// tokens, token files or login emails. It scopes the response cache and is
+	// never logged.
+	identity string
+	// fetch returns a token from the source
+	fetch func(ctx context.Context, c *FleetDMClient) (string, error)
+	// refreshable sources are fetched again when the server answers 401,
+	// e.g. a rotated token file or an expired login session
+	refreshable bool
+}
+
+// resolveCredentialSource picks the credential source for a connection. At most one
+// of api_token, api_token_file, api_token_command and email/password may be set in
+// the .spc file. If none is, the FLEETDM_API_TOKEN environment variable is used.
+func resolveCredentialSource(config Config) (*credentialSource, error) {
+	var sources []*credentialSource
+
+	if config.APIToken != nil && *config.APIToken != "" {
+		token := *config.APIToken
+		sources = append(sources, &credentialSource{
+			name:     "spc_api_token",
+			identity: "token:" + token,
+			fetch:    func(context.Context, *FleetDMClient) (string, error) { return token, nil },
+		})
+	}
+	if config.APITokenFile != nil && *config.APITokenFile != "" {
+		path := *config.APITokenFile
+		sources = append(sources, &credentialSource{
+			name:        "spc_api_token_file",
+			identity:    "token_file:" + path,
+			fetch:       func(context.Context, *FleetDMClient) (string, error) { return readTokenFile(path) },
+			refreshable: true,
+		})
+	}
+	if config.APITokenCommand != nil && *config.APITokenCommand != "" {
+		command := *config.APITokenCommand
+		sources = append(sources, &credentialSource{
+			name:        "spc_api_token_command",
+			identity:    "token_command:" + command,
+			fetch:       func(ctx context.Context, _ *FleetDMClient) (string, error) { return runTokenCommand(ctx, command) },
+			refreshable: true,
+		})
+	}
+	email, password := "", ""
+	if config.Email != nil {
+		email = *config.Email
+	}
+	if config.Password != nil {
+		password = *config.Password
+	}
+	if email != "" || password != "" {
+		if email == "" || password == "" {
+			return nil, errors.New("email and password must be configured together")
+		}
+		sources = append(sources, &credentialSource{
+			name:        "spc_email_password_login",
+			identity:    "login:" + email,
+			fetch:       func(ctx context.Context, c *FleetDMClient) (string, error) { return c.login(ctx, email, password) },
+			refreshable: true,
+		})
+	}
+
+	switch len(sources) {
+	case 0:
+		envToken := os.Getenv("FLEETDM_API_TOKEN")
+		if envToken == "" {
+			return nil, errors.New("api_token, api_token_file, api_token_command or email/password must be configured in fleetdm.spc, or the token set via FLEETDM_API_TOKEN environment variable")
+		}
+		return &credentialSource{
+			name:     "env_FLEETDM_API_TOKEN",
+			identity: "token:" + envToken,
+			fetch:    func(context.Context, *FleetDMClient) (string, error) { return envToken, nil },
+		}, nil
+	case 1:
+		return sources[0], nil
+	}
+
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = strings.TrimPrefix(source.name, "spc_")
+	}
+	return nil, fmt.Errorf("only one credential source may be configured in fleetdm.spc, found: %s", strings.Join(names, ", "))
+}
+
+// readTokenFile reads an API token from a file, ignoring surrounding whitespace.
+// The file is read again on every refresh, so rotated tokens are picked up.
+func readTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading api_token_file '%s': %w", path, err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("api_token_file '%s' is empty", path)
+	}
+	return token, nil
+}
+
+// runTokenCommand runs api_token_command through the system shell and returns its
+// trimmed stdout as the token. Stderr is only used to explain failures.
+func runTokenCommand(ctx context.Context, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, apiTokenCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("api_token_command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", errors.New("api_token_command did not print a token on stdout")
+	}
+	return token, nil
+}
+
+// loginResponse is the body returned by POST /api/v1/fleet/login.
+type loginResponse struct {
+	Token string `json:"token"`
+}
+
+// login exchanges an email and password for a session token using Fleet's /login
+// endpoint. Users with SSO or two-factor authentication enabled cannot log in this way.
+func (c *FleetDMClient) login(ctx context.Context, email, password string) (string, error) {
+	body, err := json.Marshal(map[string]string{"email": email, "password": password})
+	if err != nil {
+		return "", err
+	}
+	loginURL := c.BaseURL + "login"
+
+	req, err := http.NewRequestWithContext(ctx, "POST", loginURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("error creating login request for %s: %w", loginURL, err)
+	}
+	for name, values := range c.Headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error performing login request to %s: %w", loginURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// The body is not included as it may echo back the submitted credentials
+		return "", fmt.Errorf("login to %s as %s failed with status %s", loginURL, email, resp.Status)
+	}
+
+	var response loginResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&response); err != nil {
+		return "", fmt.Errorf("error decoding login response from %s: %w", loginURL, err)
+	}
+	if response.Token == "" {
+		return "", fmt.Errorf("login response from %s did not include a token", loginURL)
+	}
+	c.log(ctx).Info("FleetDMClient.login", "email", email, "session_token_obtained", true)
+	return response.Token, nil
+}
+
+// token returns the API token currently used by the client.
+func (c *FleetDMClient) token() string {
+	c.tokenMutex.RLock()
+	defer c.tokenMutex.RUnlock()
+	return c.apiToken
+}
+
+// refreshToken fetches a new token from a refreshable credential source after the
+// server rejected staleToken. Concurrent callers that saw the same stale token
+// share one refresh. It returns false if the source cannot be refreshed.
+func (c *FleetDMClient) refreshToken(ctx context.Context, staleToken string) (bool, error) {
+	if c.credentials == nil || !c.credentials.refreshable {
+		return false, nil
+	}
+
+	c.tokenMutex.Lock()
+	defer c.tokenMutex.Unlock()
+
+	if c.apiToken != staleToken {
+		// Another hydrate refreshed the token while we waited for the lock
+		return true, nil
+	}
+	c.log(ctx).Info("FleetDMClient.refreshToken", "api_token_source", c.credentials.name)
+	token, err := c.credentials.fetch(ctx, c)
+	if err != nil {
+		return false, fmt.Errorf("error refreshing API token from %s: %w", c.credentials.name, err)
+	}
+	c.apiToken = token
+	return true, nil
+}
@@ -0,0 +1,92 @@
+package fleetapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// errorBodySnippetLength is the maximum number of body bytes included in errors and logs.
+const errorBodySnippetLength = 500
+
+// FleetAPIErrorDetail is one entry of the `errors` array in a Fleet error response.
+type FleetAPIErrorDetail struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// FleetAPIError is returned by FleetDMClient.Get when the API answers with a non-2xx status.
+// Fleet error bodies look like:
+//
+//	{"message": "Resource Not Found", "errors": [{"name": "base", "reason": "..."}], "uuid": "..."}
+type FleetAPIError struct {
+	StatusCode int
+	Status     string
+	URL        string
+	Message    string
+	Errors     []FleetAPIErrorDetail
+	// RequestID is Fleet's error uuid, or the X-Request-Id header when the body has none
+	RequestID string
+	// Body holds a snippet of the raw body when it is not a Fleet JSON error
+	Body string
+}
+
+// Error implements the error interface.
+func (e *FleetAPIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "API request to %s failed with status %s", e.URL, e.Status)
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	for _, detail := range e.Errors {
+		if detail.Name != "" && detail.Name != "base" {
+			fmt.Fprintf(&b, "; %s: %s", detail.Name, detail.Reason)
+		} else if detail.Reason != "" {
+			fmt.Fprintf(&b, "; %s", detail.Reason)
+		}
+	}
+	if e.Message == "" && len(e.Errors) == 0 && e.Body != "" {
+		fmt.Fprintf(&b, ": %s", e.Body)
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " (request id %s)", e.RequestID)
+	}
+	return b.String()
+}
+
+// newFleetAPIError builds a FleetAPIError from a non-2xx response and its body.
+func newFleetAPIError(resp *http.Response, requestURL string, body []byte) *FleetAPIError {
+	apiErr := &FleetAPIError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		URL:        redactURL(requestURL),
+		RequestID:  resp.Header.Get("X-Request-Id"),
+	}
+
+	var payload struct {
+		Message string                `json:"message"`
+		Errors  []FleetAPIErrorDetail `json:"errors"`
+		UUID    string                `json:"uuid"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || (payload.Message == "" && len(payload.Errors) == 0) {
+		apiErr.Body = bodySnippet(body)
+		return apiErr
+	}
+	apiErr.Message = payload.Message
+	apiErr.Errors = payload.Errors
+	if payload.UUID != "" {
+		apiErr.RequestID = payload.UUID
+	}
+	return apiErr
+}
+
+// bodySnippet returns at most errorBodySnippetLength bytes of a response body,
+// with sensitive values masked by redactJSON.
+func bodySnippet(body []byte) string {
+	body = redactJSON(body)
+	if len(body) <= errorBodySnippetLength {
+		return string(body)
+	}
+	return string(body[:errorBodySnippetLength]) + "..."
+}
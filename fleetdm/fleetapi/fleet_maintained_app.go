@@ -0,0 +1,50 @@
+package fleetapi
+
+import (
+	"context"
+	"net/url"
+)
+
+// FleetMaintainedApp represents a Fleet-maintained app.
+// Refer to: https://fleetdm.com/docs/rest-api/rest-api#list-fleet-maintained-apps
+type FleetMaintainedApp struct {
+	ID              uint     `json:"id"`
+	Name            string   `json:"name"`
+	Slug            string   `json:"slug"`
+	Platform        string   `json:"platform"`
+	Version         *string  `json:"version,omitempty"`
+	SoftwareTitleID *uint    `json:"software_title_id"`
+	Categories      []string `json:"categories"`
+}
+
+// ListFleetMaintainedAppsResponse is the expected structure for the list Fleet-maintained apps API call.
+type ListFleetMaintainedAppsResponse struct {
+	FleetMaintainedApps []FleetMaintainedApp `json:"fleet_maintained_apps"`
+	Meta                struct {
+		HasNextResults     bool `json:"has_next_results"`
+		HasPreviousResults bool `json:"has_previous_results"`
+	} `json:"meta"`
+}
+
+// ListFleetMaintainedAppsOptions filters GET /api/v1/fleet/software/fleet_maintained_apps.
+type ListFleetMaintainedAppsOptions struct {
+	ListOptions
+	TeamID *uint // Sets software_title_id on the apps already added to the team
+}
+
+// ListFleetMaintainedApps lists the Fleet-maintained apps until fn returns false.
+// It requires Fleet Premium and Fleet 4.57.0 or later.
+func (c *FleetDMClient) ListFleetMaintainedApps(ctx context.Context, opts ListFleetMaintainedAppsOptions, fn func(app FleetMaintainedApp) bool) error {
+	params := url.Values{}
+	setUint(params, "team_id", opts.TeamID)
+
+	pages := paginator[FleetMaintainedApp]{
+		Name:     "FleetDMClient.ListFleetMaintainedApps",
+		Endpoint: "software/fleet_maintained_apps",
+		Params:   params,
+		ItemsKey: "fleet_maintained_apps",
+		Mode:     paginateByMeta,
+		PageSize: 10000,
+	}
+	return pages.walk(ctx, c, opts.ListOptions, fn)
+}
@@ -0,0 +1,195 @@
+package fleetapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+)
+
+// HostUser represents a user on a specific host.
+type HostUser struct {
+	UID       int    `json:"uid"`
+	Username  string `json:"username"`
+	Type      string `json:"type"`
+	Groupname string `json:"groupname"`
+	Shell     string `json:"shell"`
+}
+
+// HostPolicy represents a policy's status on a specific host.
+type HostPolicy struct {
+	ID                    uint      `json:"id"`
+	Name                  string    `json:"name"`
+	Query                 string    `json:"query"`
+	Critical              bool      `json:"critical"`
+	Description           string    `json:"description"`
+	AuthorID              *uint     `json:"author_id"`
+	AuthorName            string    `json:"author_name"`
+	AuthorEmail           string    `json:"author_email"`
+	TeamID                *uint     `json:"team_id"`
+	Resolution            string    `json:"resolution"`
+	Platform              string    `json:"platform"`
+	CalendarEventsEnabled bool      `json:"calendar_events_enabled"`
+	CreatedAt             FleetTime `json:"created_at"`
+	UpdatedAt             FleetTime `json:"updated_at"`
+	Response              string    `json:"response"` // e.g., "pass", "fail"
+}
+
+// HostLabel represents a label applied to a specific host.
+type HostLabel struct {
+	ID                  uint      `json:"id"`
+	CreatedAt           FleetTime `json:"created_at"`
+	UpdatedAt           FleetTime `json:"updated_at"`
+	Name                string    `json:"name"`
+	Description         string    `json:"description"`
+	Query               string    `json:"query"`
+	Platform            string    `json:"platform"`
+	LabelType           string    `json:"label_type"`
+	LabelMembershipType string    `json:"label_membership_type"`
+	AuthorID            *uint     `json:"author_id,omitempty"`
+}
+
+// HostIssues represents the 'issues' object for a host.
+type HostIssues struct {
+	FailingPoliciesCount         int `json:"failing_policies_count"`
+	CriticalVulnerabilitiesCount int `json:"critical_vulnerabilities_count"`
+	TotalIssuesCount             int `json:"total_issues_count"`
+}
+
+// HostMDM represents the 'mdm' object for a host.
+type HostMDM struct {
+	EnrollmentStatus       string  `json:"enrollment_status"`
+	DEPProfileError        bool    `json:"dep_profile_error"`
+	ServerURL              *string `json:"server_url"`
+	Name                   *string `json:"name"`
+	EncryptionKeyAvailable bool    `json:"encryption_key_available"`
+	ConnectedToFleet       *bool   `json:"connected_to_fleet"`
+}
+
+// DeviceMappingItem represents an item within the device_mapping array.
+type DeviceMappingItem struct {
+	Email  string `json:"email"`
+	Source string `json:"source"`
+}
+
+// Host represents a FleetDM host.
+// Refer to: https://fleetdm.com/docs/rest-api/rest-api#list-hosts
+type Host struct {
+	ID                          int                 `json:"id"`
+	CreatedAt                   FleetTime           `json:"created_at"`
+	UpdatedAt                   FleetTime           `json:"updated_at"`
+	SoftwareUpdatedAt           *FleetTime          `json:"software_updated_at"`
+	DetailUpdatedAt             FleetTime           `json:"detail_updated_at"`
+	LabelUpdatedAt              FleetTime           `json:"label_updated_at"`
+	PolicyUpdatedAt             FleetTime           `json:"policy_updated_at"`
+	LastEnrolledAt              FleetTime           `json:"last_enrolled_at"`
+	SeenTime                    FleetTime           `json:"seen_time"`
+	RefetchRequested            bool                `json:"refetch_requested"`
+	UUID                        string              `json:"uuid"`
+	Hostname                    string              `json:"hostname"`
+	DisplayName                 string              `json:"display_name"`
+	DisplayText                 string              `json:"display_text"`
+	ComputerName                string              `json:"computer_name"`
+	Platform                    string              `json:"platform"`
+	PlatformLike                string              `json:"platform_like"`
+	OsVersion                   string              `json:"os_version"`
+	Build                       string              `json:"build"`
+	CodeName                    string              `json:"code_name"`
+	Uptime                      int64               `json:"uptime"` // Nanoseconds
+	Memory                      int64               `json:"memory"` // bytes
+	CPUType                     string              `json:"cpu_type"`
+	CPUSubtype                  string              `json:"cpu_subtype"`
+	CPUBrand                    string              `json:"cpu_brand"`
+	CPUPhysicalCores            int                 `json:"cpu_physical_cores"`
+	CPULogicalCores             int                 `json:"cpu_logical_cores"`
+	HardwareVendor              string              `json:"hardware_vendor"`
+	HardwareModel               string              `json:"hardware_model"`
+	HardwareVersion             string              `json:"hardware_version"`
+	HardwareSerial              string              `json:"hardware_serial"`
+	PrimaryIP                   string              `json:"primary_ip"`
+	PrimaryMac                  string              `json:"primary_mac"`
+	PublicIP                    string              `json:"public_ip"`
+	OrbitVersion                *string             `json:"orbit_version"`
+	FleetDesktopVersion         *string             `json:"fleet_desktop_version"`
+	ScriptsEnabled              *bool               `json:"scripts_enabled"`
+	OsqueryVersion              *string             `json:"osquery_version"`
+	TeamID                      *int                `json:"team_id"`
+	TeamName                    *string             `json:"team_name"`
+	DistributedInterval         *int                `json:"distributed_interval"`
+	ConfigTLSRefresh            *int                `json:"config_tls_refresh"`
+	LoggerTLSPeriod             *int                `json:"logger_tls_period"`
+	PackStats                   *json.RawMessage    `json:"pack_stats"`
+	GigsDiskSpaceAvailable      float64             `json:"gigs_disk_space_available"`
+	PercentDiskSpaceAvailable   float64             `json:"percent_disk_space_available"`
+	GigsTotalDiskSpace          float64             `json:"gigs_total_disk_space"`
+	Status                      string              `json:"status"`
+	Issues                      *HostIssues         `json:"issues"`
+	MDM                         *HostMDM            `json:"mdm"`
+	RefetchCriticalQueriesUntil *FleetTime          `json:"refetch_critical_queries_until"`
+	LastRestartedAt             *FleetTime          `json:"last_restarted_at"`
+	Users                       []HostUser          `json:"users,omitempty"`
+	Policies                    []HostPolicy        `json:"policies,omitempty"`
+	Labels                      []HostLabel         `json:"labels,omitempty"`
+	DeviceMapping               []DeviceMappingItem `json:"device_mapping,omitempty"` // Updated from *json.RawMessage
+}
+
+// ListHostsResponse is the expected structure for the list hosts API call.
+type ListHostsResponse struct {
+	Hosts []Host `json:"hosts"`
+}
+
+// ListHostsOptions filters GET /api/v1/fleet/hosts.
+type ListHostsOptions struct {
+	ListOptions
+	OrderDirection      string // "asc" or "desc" by id, Fleet's default when empty
+	Query               string // Matches hostname, uuid, hardware_serial and primary_ip
+	Status              string // e.g. "online", "offline", "new", "missing"
+	TeamID              *uint
+	OSVersionID         *uint
+	Vulnerability       string // CVE identifier
+	SoftwareVersionID   *uint
+	SoftwareTitleID     *uint
+	PolicyID            *uint
+	PolicyResponse      string // "passing" or "failing", requires PolicyID
+	MDMEnrollmentStatus string
+	LowDiskSpace        *int   // Gigabytes, Fleet Premium only
+	ProfileUUID         string // Configuration profile, requires ProfileStatus
+	ProfileStatus       string // "verified", "verifying", "pending" or "failed", requires ProfileUUID
+	// Populate lists the optional fields Fleet computes for each host on request,
+	// e.g. "populate_policies"
+	Populate []string
+}
+
+// ListHosts lists the hosts matching opts until fn returns false.
+func (c *FleetDMClient) ListHosts(ctx context.Context, opts ListHostsOptions, fn func(host Host) bool) error {
+	params := url.Values{}
+	params.Add("order_key", "id")
+	setString(params, "order_direction", opts.OrderDirection)
+	for _, populate := range opts.Populate {
+		params.Set(populate, "true")
+	}
+	setString(params, "query", opts.Query)
+	setUint(params, "team_id", opts.TeamID)
+	setString(params, "status", opts.Status)
+	setUint(params, "os_version_id", opts.OSVersionID)
+	setString(params, "vulnerability", opts.Vulnerability)
+	setUint(params, "software_version_id", opts.SoftwareVersionID)
+	setUint(params, "software_title_id", opts.SoftwareTitleID)
+	setUint(params, "policy_id", opts.PolicyID)
+	setString(params, "policy_response", opts.PolicyResponse)
+	setString(params, "mdm_enrollment_status", opts.MDMEnrollmentStatus)
+	setInt(params, "low_disk_space", opts.LowDiskSpace)
+	setString(params, "profile_uuid", opts.ProfileUUID)
+	setString(params, "profile_status", opts.ProfileStatus)
+
+	c.log(ctx).Debug("FleetDMClient.ListHosts", "request_params", redactQuery(params))
+
+	pages := paginator[Host]{
+		Name:     "FleetDMClient.ListHosts",
+		Endpoint: "hosts",
+		Params:   params,
+		ItemsKey: "hosts",
+		Mode:     paginateByCount,
+		PageSize: 100,
+	}
+	return pages.walk(ctx, c, opts.ListOptions, fn)
+}
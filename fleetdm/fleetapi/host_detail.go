@@ -0,0 +1,191 @@
+package fleetapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// HostMDMDetail represents the rich 'mdm' object.
+type HostMDMDetail struct {
+	EncryptionKeyAvailable bool             `json:"encryption_key_available"`
+	EnrollmentStatus       string           `json:"enrollment_status"`
+	Name                   *string          `json:"name"`
+	ConnectedToFleet       *bool            `json:"connected_to_fleet"`
+	ServerURL              *string          `json:"server_url"`
+	DeviceStatus           string           `json:"device_status"`
+	PendingAction          string           `json:"pending_action"`
+	MacOSSettings          *json.RawMessage `json:"macos_settings"`
+	MacOSSetup             *json.RawMessage `json:"macos_setup"`
+	OsSettings             *json.RawMessage `json:"os_settings"`
+	Profiles               *json.RawMessage `json:"profiles"`
+}
+
+// HostMDMProfile is the status of a configuration profile on a host.
+type HostMDMProfile struct {
+	ProfileUUID   string  `json:"profile_uuid"`
+	Name          string  `json:"name"`
+	OperationType string  `json:"operation_type"` // "install" or "remove"
+	Status        *string `json:"status"`         // "verified", "verifying", "pending" or "failed"; null while pending
+	Detail        string  `json:"detail"`         // Error details of failed profiles
+	Platform      string  `json:"platform"`       // e.g. "darwin", "windows"
+}
+
+// MDMProfiles decodes the configuration profiles of the host, which Profiles
+// keeps as returned by Fleet.
+func (m *HostMDMDetail) MDMProfiles() ([]HostMDMProfile, error) {
+	if m == nil || m.Profiles == nil {
+		return nil, nil
+	}
+	var profiles []HostMDMProfile
+	if err := json.Unmarshal(*m.Profiles, &profiles); err != nil {
+		return nil, fmt.Errorf("error decoding mdm.profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+// HostBattery represents a battery on a host.
+type HostBattery struct {
+	CycleCount int    `json:"cycle_count"`
+	Health     string `json:"health"`
+}
+
+// HostGeometry represents the geometry part of geolocation.
+type HostGeometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+// HostGeolocation represents the geolocation of a host.
+type HostGeolocation struct {
+	CountryISO string        `json:"country_iso"`
+	CityName   string        `json:"city_name"`
+	Geometry   *HostGeometry `json:"geometry"`
+}
+
+// HostMaintenanceWindow represents a configured maintenance window.
+type HostMaintenanceWindow struct {
+	StartsAt FleetTime `json:"starts_at"`
+	Timezone string    `json:"timezone"`
+}
+
+// HostOtherEmail represents an email entry for an end user.
+type HostOtherEmail struct {
+	Email  string `json:"email"`
+	Source string `json:"source"`
+}
+
+// HostEndUser represents an end user associated with a device.
+type HostEndUser struct {
+	IdpInfoUpdatedAt FleetTime        `json:"idp_info_updated_at"`
+	IdpID            string           `json:"idp_id"`
+	IdpUsername      string           `json:"idp_username"`
+	IdpFullName      string           `json:"idp_full_name"`
+	IdpGroups        []string         `json:"idp_groups"`
+	OtherEmails      []HostOtherEmail `json:"other_emails"`
+}
+
+// HostSoftware represents a software item on a specific host.
+type HostSoftware struct {
+	ID               uint             `json:"id"`
+	Name             string           `json:"name"`
+	Version          string           `json:"version"`
+	Source           string           `json:"source"`
+	Browser          string           `json:"browser"`
+	BundleIdentifier string           `json:"bundle_identifier"`
+	LastOpenedAt     *FleetTime       `json:"last_opened_at"`
+	GeneratedCPE     string           `json:"generated_cpe"`
+	Vulnerabilities  *json.RawMessage `json:"vulnerabilities"`
+	InstalledPaths   []string         `json:"installed_paths"`
+}
+
+// HostDetail represents the full, rich host object from GET /hosts/:id
+type HostDetail struct {
+	ID                          int                    `json:"id"`
+	CreatedAt                   FleetTime              `json:"created_at"`
+	UpdatedAt                   FleetTime              `json:"updated_at"`
+	SoftwareUpdatedAt           FleetTime              `json:"software_updated_at"`
+	DetailUpdatedAt             FleetTime              `json:"detail_updated_at"`
+	LabelUpdatedAt              FleetTime              `json:"label_updated_at"`
+	PolicyUpdatedAt             FleetTime              `json:"policy_updated_at"`
+	LastEnrolledAt              FleetTime              `json:"last_enrolled_at"`
+	LastMdmCheckedInAt          FleetTime              `json:"last_mdm_checked_in_at"`
+	LastMdmEnrolledAt           FleetTime              `json:"last_mdm_enrolled_at"`
+	LastRestartedAt             *FleetTime             `json:"last_restarted_at"`
+	RefetchCriticalQueriesUntil *FleetTime             `json:"refetch_critical_queries_until"`
+	SeenTime                    FleetTime              `json:"seen_time"`
+	RefetchRequested            bool                   `json:"refetch_requested"`
+	Hostname                    string                 `json:"hostname"`
+	UUID                        string                 `json:"uuid"`
+	Platform                    string                 `json:"platform"`
+	OsqueryVersion              string                 `json:"osquery_version"`
+	OrbitVersion                *string                `json:"orbit_version"`
+	FleetDesktopVersion         *string                `json:"fleet_desktop_version"`
+	ScriptsEnabled              *bool                  `json:"scripts_enabled"`
+	OsVersion                   string                 `json:"os_version"`
+	Build                       string                 `json:"build"`
+	PlatformLike                string                 `json:"platform_like"`
+	CodeName                    string                 `json:"code_name"`
+	Uptime                      int64                  `json:"uptime"`
+	Memory                      int64                  `json:"memory"`
+	CPUType                     string                 `json:"cpu_type"`
+	CPUSubtype                  string                 `json:"cpu_subtype"`
+	CPUBrand                    string                 `json:"cpu_brand"`
+	CPUPhysicalCores            int                    `json:"cpu_physical_cores"`
+	CPULogicalCores             int                    `json:"cpu_logical_cores"`
+	HardwareVendor              string                 `json:"hardware_vendor"`
+	HardwareModel               string                 `json:"hardware_model"`
+	HardwareVersion             string                 `json:"hardware_version"`
+	HardwareSerial              string                 `json:"hardware_serial"`
+	ComputerName                string                 `json:"computer_name"`
+	DisplayName                 string                 `json:"display_name"`
+	PublicIP                    string                 `json:"public_ip"`
+	PrimaryIP                   string                 `json:"primary_ip"`
+	PrimaryMac                  string                 `json:"primary_mac"`
+	DistributedInterval         int                    `json:"distributed_interval"`
+	ConfigTLSRefresh            int                    `json:"config_tls_refresh"`
+	LoggerTLSPeriod             int                    `json:"logger_tls_period"`
+	TeamID                      *int                   `json:"team_id"`
+	TeamName                    *string                `json:"team_name"`
+	GigsDiskSpaceAvailable      float64                `json:"gigs_disk_space_available"`
+	PercentDiskSpaceAvailable   float64                `json:"percent_disk_space_available"`
+	GigsTotalDiskSpace          float64                `json:"gigs_total_disk_space"`
+	DiskEncryptionEnabled       *bool                  `json:"disk_encryption_enabled"`
+	Status                      string                 `json:"status"`
+	DisplayText                 string                 `json:"display_text"`
+	Additional                  *json.RawMessage       `json:"additional"`
+	Issues                      *HostIssues            `json:"issues"`
+	Batteries                   []HostBattery          `json:"batteries"`
+	Geolocation                 *HostGeolocation       `json:"geolocation"`
+	MaintenanceWindow           *HostMaintenanceWindow `json:"maintenance_window"`
+	Users                       []HostUser             `json:"users"`
+	EndUsers                    []HostEndUser          `json:"end_users"`
+	Labels                      []HostLabel            `json:"labels"`
+	Packs                       *json.RawMessage       `json:"packs"`
+	Policies                    []HostPolicy           `json:"policies"`
+	Software                    []HostSoftware         `json:"software"`
+	MDM                         *HostMDMDetail         `json:"mdm"`
+}
+
+// GetHostOptions selects the optional fields of GET /api/v1/fleet/hosts/:id.
+type GetHostOptions struct {
+	// Populate lists the optional fields Fleet computes on request, e.g. "populate_policies"
+	Populate []string
+}
+
+// GetHost returns the full details of the host with the given id.
+func (c *FleetDMClient) GetHost(ctx context.Context, id uint, opts GetHostOptions) (*HostDetail, error) {
+	params := url.Values{}
+	for _, populate := range opts.Populate {
+		params.Set(populate, "true")
+	}
+
+	var response struct {
+		Host HostDetail `json:"host"`
+	}
+	if _, err := c.Get(ctx, fmt.Sprintf("hosts/%d", id), params, &response); err != nil {
+		return nil, err
+	}
+	return &response.Host, nil
+}
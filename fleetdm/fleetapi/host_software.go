@@ -0,0 +1,56 @@
+package fleetapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// HostSoftwareInstalledVersion is a version of a software title installed on a host.
+type HostSoftwareInstalledVersion struct {
+	SoftwareID       uint       `json:"software_id"`
+	Version          string     `json:"version"`
+	Source           string     `json:"source"`
+	BundleIdentifier string     `json:"bundle_identifier"`
+	LastOpenedAt     *FleetTime `json:"last_opened_at"`
+	GeneratedCPE     string     `json:"generated_cpe"`   // Not reported by every Fleet version
+	Vulnerabilities  []string   `json:"vulnerabilities"` // List of CVE strings
+	InstalledPaths   []string   `json:"installed_paths"` // Paths the version was found at, where the source reports them
+}
+
+// HostSoftwareTitle is a software title on a host, with its installed versions.
+// Refer to: https://fleetdm.com/docs/rest-api/rest-api#get-hosts-software
+type HostSoftwareTitle struct {
+	ID                uint                           `json:"id"` // Software title ID
+	Name              string                         `json:"name"`
+	Source            string                         `json:"source"`
+	Status            *string                        `json:"status"` // Install status of a Fleet-managed package, e.g. "installed", "pending_install"
+	InstalledVersions []HostSoftwareInstalledVersion `json:"installed_versions"`
+	SoftwarePackage   interface{}                    `json:"software_package"`
+	AppStoreApp       interface{}                    `json:"app_store_app"`
+}
+
+// ListHostSoftwareOptions filters GET /api/v1/fleet/hosts/:id/software.
+type ListHostSoftwareOptions struct {
+	ListOptions
+	Query      string // Matches the software name
+	Vulnerable *bool  // Only titles with a vulnerable installed version
+}
+
+// ListHostSoftware lists the software titles on the host with the given id until
+// fn returns false.
+func (c *FleetDMClient) ListHostSoftware(ctx context.Context, hostID uint, opts ListHostSoftwareOptions, fn func(title HostSoftwareTitle) bool) error {
+	params := url.Values{}
+	setString(params, "query", opts.Query)
+	setBool(params, "vulnerable", opts.Vulnerable)
+
+	pages := paginator[HostSoftwareTitle]{
+		Name:     "FleetDMClient.ListHostSoftware",
+		Endpoint: fmt.Sprintf("hosts/%d/software", hostID),
+		Params:   params,
+		ItemsKey: "software",
+		Mode:     paginateByMeta,
+		PageSize: 100,
+	}
+	return pages.walk(ctx, c, opts.ListOptions, fn)
+}
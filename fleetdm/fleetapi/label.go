@@ -0,0 +1,101 @@
+package fleetapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// Label represents a FleetDM label.
+// Refer to: https://fleetdm.com/docs/rest-api/rest-api#label-object
+type Label struct {
+	ID                  uint      `json:"id"`
+	CreatedAt           FleetTime `json:"created_at"`
+	UpdatedAt           FleetTime `json:"updated_at"`
+	Name                string    `json:"name"`
+	Description         string    `json:"description"`
+	Query               string    `json:"query"`                 // The SQL query for dynamic labeling
+	Platform            string    `json:"platform"`              // e.g., "darwin", "windows", "linux", "" for all
+	LabelType           string    `json:"label_type"`            // "regular" or "builtin"
+	LabelMembershipType string    `json:"label_membership_type"` // "dynamic" or "manual" (manual not via API yet for creation)
+	HostCount           int       `json:"host_count"`
+	DisplayText         string    `json:"display_text"` // Usually same as name
+	BuiltIn             bool      `json:"built_in"`     // Derived from label_type == "builtin"
+	// Hosts field is not typically included in list/get label, but on a separate endpoint like /labels/{id}/hosts
+}
+
+// ListLabelsResponse for `GET /api/v1/fleet/labels`
+// The API returns {"labels": [...]}
+type ListLabelsResponse struct {
+	Labels []Label `json:"labels"`
+	// Meta for pagination if API supports it
+}
+
+// GetLabelResponse for `GET /api/v1/fleet/labels/{id}`
+// The API returns {"label": {...}}
+type GetLabelResponse struct {
+	Label Label `json:"label"`
+}
+
+// ListLabelsOptions filters GET /api/v1/fleet/labels.
+type ListLabelsOptions struct {
+	ListOptions
+	TeamID string // A team id, or "global" for global-only labels. Fleet Premium only.
+}
+
+// ListLabels lists the labels until fn returns false.
+func (c *FleetDMClient) ListLabels(ctx context.Context, opts ListLabelsOptions, fn func(label Label) bool) error {
+	params := url.Values{}
+	setString(params, "team_id", opts.TeamID)
+
+	// The /labels endpoint does not specify a `meta.has_next_results`.
+	pages := paginator[Label]{
+		Name:     "FleetDMClient.ListLabels",
+		Endpoint: "labels",
+		Params:   params,
+		ItemsKey: "labels",
+		Mode:     paginateByCount,
+		PageSize: 50, // API default is 20, max 100
+	}
+	return pages.walk(ctx, c, opts.ListOptions, fn)
+}
+
+// GetLabel returns the label with the given id.
+func (c *FleetDMClient) GetLabel(ctx context.Context, id uint) (*Label, error) {
+	var response struct {
+		Label Label `json:"label"`
+	}
+	if _, err := c.Get(ctx, fmt.Sprintf("labels/%d", id), nil, &response); err != nil {
+		return nil, err
+	}
+	return &response.Label, nil
+}
+
+// ListLabelHostsOptions filters GET /api/v1/fleet/labels/:id/hosts.
+type ListLabelHostsOptions struct {
+	ListOptions
+	Query  string // Matches hostname, uuid, hardware_serial and primary_ip
+	Status string // e.g. "online", "offline", "new", "missing"
+	TeamID *uint
+}
+
+// ListLabelHosts lists the member hosts of the label with the given id until fn
+// returns false. Members of manual and dynamic labels are listed alike.
+func (c *FleetDMClient) ListLabelHosts(ctx context.Context, labelID uint, opts ListLabelHostsOptions, fn func(host Host) bool) error {
+	params := url.Values{}
+	params.Add("order_key", "id")
+	params.Add("order_direction", "asc")
+	setString(params, "query", opts.Query)
+	setString(params, "status", opts.Status)
+	setUint(params, "team_id", opts.TeamID)
+
+	pages := paginator[Host]{
+		Name:     "FleetDMClient.ListLabelHosts",
+		Endpoint: fmt.Sprintf("labels/%d/hosts", labelID),
+		Params:   params,
+		ItemsKey: "hosts",
+		Mode:     paginateByCount,
+		PageSize: 100,
+	}
+	return pages.walk(ctx, c, opts.ListOptions, fn)
+}
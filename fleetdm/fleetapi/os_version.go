@@ -0,0 +1,75 @@
+package fleetapi
+
+import (
+	"context"
+	"net/url"
+)
+
+// OSVersionVulnerability represents a vulnerability associated with an OS version.
+type OSVersionVulnerability struct {
+	CVE               string     `json:"cve"`
+	DetailsLink       string     `json:"details_link"`
+	CreatedAt         *FleetTime `json:"created_at,omitempty"`
+	CVSSScore         *float64   `json:"cvss_score,omitempty"`
+	EPSSProbability   *float64   `json:"epss_probability,omitempty"`
+	CISAKnownExploit  *bool      `json:"cisa_known_exploit,omitempty"`
+	CVEPublished      *FleetTime `json:"cve_published,omitempty"`
+	CVEDescription    *string    `json:"cve_description,omitempty"`
+	ResolvedInVersion *string    `json:"resolved_in_version,omitempty"`
+}
+
+// OSVersion represents an operating system version in FleetDM.
+// Refer to: https://fleetdm.com/docs/rest-api/rest-api#list-operating-systems
+type OSVersion struct {
+	OSVersionID          uint                     `json:"os_version_id"`
+	HostsCount           uint                     `json:"hosts_count"`
+	Name                 string                   `json:"name"`
+	NameOnly             string                   `json:"name_only"`
+	Version              string                   `json:"version"`
+	Platform             string                   `json:"platform"`
+	GeneratedCPEs        []string                 `json:"generated_cpes"`
+	Vulnerabilities      []OSVersionVulnerability `json:"vulnerabilities"`
+	VulnerabilitiesCount uint                     `json:"vulnerabilities_count"`
+}
+
+// ListOSVersionsResponse is the expected structure for the list OS versions API call.
+type ListOSVersionsResponse struct {
+	OSVersions []OSVersion `json:"os_versions"`
+	Meta       struct {
+		HasNextResults     bool `json:"has_next_results"`
+		HasPreviousResults bool `json:"has_previous_results"`
+	} `json:"meta"`
+	Count           int        `json:"count"`
+	CountsUpdatedAt *FleetTime `json:"counts_updated_at"`
+}
+
+// ListOSVersionsOptions filters GET /api/v1/fleet/os_versions.
+type ListOSVersionsOptions struct {
+	ListOptions
+	TeamID    *uint
+	Platform  string // e.g. "darwin"
+	OSName    string // Requires OSVersion
+	OSVersion string // Requires OSName
+}
+
+// ListOSVersions lists the operating system versions, most installed first,
+// until fn returns false.
+func (c *FleetDMClient) ListOSVersions(ctx context.Context, opts ListOSVersionsOptions, fn func(osVersion OSVersion) bool) error {
+	params := url.Values{}
+	params.Add("order_key", "hosts_count")
+	params.Add("order_direction", "desc")
+	setUint(params, "team_id", opts.TeamID)
+	setString(params, "platform", opts.Platform)
+	setString(params, "os_name", opts.OSName)
+	setString(params, "os_version", opts.OSVersion)
+
+	pages := paginator[OSVersion]{
+		Name:     "FleetDMClient.ListOSVersions",
+		Endpoint: "os_versions", // Endpoint is /api/v1/fleet/os_versions
+		Params:   params,
+		ItemsKey: "os_versions",
+		Mode:     paginateByMeta,
+		PageSize: 10000,
+	}
+	return pages.walk(ctx, c, opts.ListOptions, fn)
+}
@@ -0,0 +1,72 @@
+package fleetapi
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Pack represents a query pack in FleetDM.
+// Refer to: https://fleetdm.com/docs/rest-api/rest-api#pack-object
+type Pack struct {
+	ID                         uint             `json:"id"`
+	CreatedAt                  FleetTime        `json:"created_at"`
+	UpdatedAt                  FleetTime        `json:"updated_at"`
+	Name                       string           `json:"name"`
+	Description                string           `json:"description"`
+	Platform                   string           `json:"platform"` // Comma-separated list or empty for all
+	Disabled                   bool             `json:"disabled"`
+	Type                       string           `json:"type"`                          // e.g., "global", "team"
+	TeamID                     *uint            `json:"team_id"`                       // Null if global
+	TargetCount                int              `json:"target_count"`                  // Number of targets (hosts/labels)
+	TotalScheduledQueriesCount int              `json:"total_scheduled_queries_count"` // Total scheduled queries in the pack
+	Targets                    *json.RawMessage `json:"targets,omitempty"`             // Only on GET /packs/{id}, complex object { hosts: [], labels: [], teams: [] }
+	ScheduledQueries           []ScheduledQuery `json:"scheduled_queries,omitempty"`   // Only on GET /packs/{id}
+	AgentOptions               *json.RawMessage `json:"agent_options,omitempty"`       // Present if pack is for a team
+	HostIDs                    []uint           `json:"host_ids,omitempty"`            // Host IDs this pack is targeted to (from GET /packs/{id})
+	LabelIDs                   []uint           `json:"label_ids,omitempty"`           // Label IDs this pack is targeted to (from GET /packs/{id})
+	TeamIDs                    []uint           `json:"team_ids,omitempty"`            // Team IDs this pack is targeted to (from GET /packs/{id}) - usually for global packs targeting teams
+}
+
+// ScheduledQuery represents a query within a pack.
+// This is similar to QuerySaved but might have pack-specific attributes like interval.
+type ScheduledQuery struct {
+	ID                uint    `json:"id"` // This is the ID of the saved query itself
+	Name              string  `json:"name"`
+	Query             string  `json:"query"` // The actual SQL of the saved query
+	Description       string  `json:"description"`
+	Interval          uint    `json:"interval"`            // Interval for this query within the pack
+	Platform          *string `json:"platform"`            // Platform for this query within the pack
+	MinOsqueryVersion *string `json:"min_osquery_version"` // Min osquery version for this query within the pack
+	Logging           string  `json:"logging"`             // snapshot, differential, differential_ignore_removals
+	Removed           bool    `json:"removed"`             // Whether the query is removed (e.g. results are logged as removed)
+	Snapshot          *bool   `json:"snapshot"`            // Whether to run as a snapshot query
+	Shard             *uint   `json:"shard"`               // Shard number for the query
+}
+
+// ListPacksResponse for `GET /api/v1/fleet/packs`
+type ListPacksResponse struct {
+	Packs []Pack `json:"packs"`
+	// Meta for pagination if API supports it for packs
+}
+
+// GetPackResponse for `GET /api/v1/fleet/packs/{id}`
+type GetPackResponse struct {
+	Pack Pack `json:"pack"`
+}
+
+// ListPacks lists the query packs until fn returns false.
+//
+// The list endpoint provides summary data. Detailed fields like targets,
+// scheduled_queries and agent_options are only returned by GET /packs/:id and
+// are empty here.
+func (c *FleetDMClient) ListPacks(ctx context.Context, opts ListOptions, fn func(pack Pack) bool) error {
+	// TODO: Add a team_id filter if the API supports it
+	pages := paginator[Pack]{
+		Name:     "FleetDMClient.ListPacks",
+		Endpoint: "packs",
+		ItemsKey: "packs",
+		Mode:     paginateByCount, // The /packs endpoint does not specify a `meta.has_next_results`
+		PageSize: 50,              // API default is 20, max 100
+	}
+	return pages.walk(ctx, c, opts, fn)
+}
@@ -0,0 +1,159 @@
+package fleetapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// paginationMode selects how a paginator decides whether another page exists.
+type paginationMode int
+
+const (
+	// paginateByCount requests page=N and stops on a page shorter than per_page.
+	// Used for endpoints which do not return a meta object.
+	paginateByCount paginationMode = iota
+	// paginateByMeta requests page=N and stops when meta.has_next_results is false.
+	// It falls back to paginateByCount for servers which omit meta.
+	paginateByMeta
+	// paginateByCursor sends the last item's cursor (or meta.next_cursor when the
+	// server provides one) as the `after` param instead of a page number.
+	paginateByCursor
+)
+
+// paginator walks a Fleet list endpoint page by page. It is the one place that
+// knows how Fleet pages results, so every list function pages the same way.
+type paginator[T any] struct {
+	// Name prefixes log lines, e.g. "FleetDMClient.ListHosts"
+	Name     string
+	Endpoint string
+	// Params are sent with every page; paging params are added by the paginator
+	Params url.Values
+	// ItemsKey is the JSON key holding the page's items, e.g. "hosts"
+	ItemsKey string
+	Mode     paginationMode
+	// PageSize is the endpoint's default per_page, overridden by ListOptions.PerPage
+	PageSize int
+	// Cursor returns the `after` value for an item, required for paginateByCursor.
+	// The endpoint must be ordered by the same key, e.g. order_key=id.
+	Cursor func(item T) string
+}
+
+// pageMeta is the pagination metadata some Fleet list endpoints return.
+type pageMeta struct {
+	HasNextResults *bool  `json:"has_next_results"`
+	NextCursor     string `json:"next_cursor"`
+}
+
+// ListOptions are the paging options taken by every List method.
+type ListOptions struct {
+	// PerPage is the page size requested, instead of the endpoint's default
+	PerPage int
+	// Limit stops listing after this many items. Pages are no larger than the limit;
+	// their size stays fixed for the whole listing, so page offsets remain consistent.
+	Limit int
+	// BeforePage, if set, is called before each page is requested, e.g. to wait
+	// on a rate limiter
+	BeforePage func(ctx context.Context)
+}
+
+// walk requests pages until the endpoint is exhausted, opts.Limit items were
+// handed to fn or fn returns false.
+func (p paginator[T]) walk(ctx context.Context, c *FleetDMClient, opts ListOptions, fn func(item T) bool) error {
+	pageSize := p.PageSize
+	if opts.PerPage > 0 {
+		pageSize = opts.PerPage
+	}
+	if opts.Limit > 0 && opts.Limit < pageSize {
+		pageSize = opts.Limit
+	}
+	listed := 0
+	page := 0
+	after := ""
+
+	for {
+		params := url.Values{}
+		for key, values := range p.Params {
+			params[key] = values
+		}
+		params.Set("per_page", strconv.Itoa(pageSize))
+		if p.Mode == paginateByCursor {
+			// Fleet requires page 0 when `after` is used
+			params.Set("page", "0")
+			if after != "" {
+				params.Set("after", after)
+			}
+		} else {
+			params.Set("page", strconv.Itoa(page))
+		}
+
+		if opts.BeforePage != nil {
+			opts.BeforePage(ctx)
+		}
+		// Items are decoded and handed to fn one at a time once the page is read
+		itemCount := 0
+		var last T
+		fields, err := c.GetStream(ctx, p.Endpoint, params, p.ItemsKey, func(dec *json.Decoder) error {
+			var item T
+			if err := dec.Decode(&item); err != nil {
+				return err
+			}
+			itemCount++
+			listed++
+			last = item
+			if !fn(item) {
+				return errStopStream
+			}
+			if listed == opts.Limit {
+				c.log(ctx).Debug(p.Name, "limit_reached", true)
+				return errStopStream
+			}
+			return nil
+		})
+		if errors.Is(err, errStopStream) {
+			return nil
+		}
+		if err != nil {
+			c.log(ctx).Error(p.Name, "api_error", err, "page", page, "params", redactQuery(params))
+			return err
+		}
+
+		var meta *pageMeta
+		if raw, ok := fields["meta"]; ok && string(raw) != "null" {
+			meta = &pageMeta{}
+			if err := json.Unmarshal(raw, meta); err != nil {
+				return fmt.Errorf("error decoding meta from %s: %w", p.Endpoint, err)
+			}
+		}
+
+		c.log(ctx).Debug(p.Name, "page_processed", page, "items_on_page", itemCount, "has_meta", meta != nil)
+
+		if !p.hasNextPage(itemCount, pageSize, meta) {
+			c.log(ctx).Debug(p.Name, "end_of_results", true, "pages", page+1)
+			return nil
+		}
+
+		page++
+		if p.Mode == paginateByCursor {
+			if meta != nil && meta.NextCursor != "" {
+				after = meta.NextCursor
+			} else {
+				after = p.Cursor(last)
+			}
+		}
+	}
+}
+
+// hasNextPage applies the paginator's stop rule to a page of itemCount items.
+func (p paginator[T]) hasNextPage(itemCount, pageSize int, meta *pageMeta) bool {
+	if itemCount == 0 {
+		return false
+	}
+	if p.Mode != paginateByCount && meta != nil && meta.HasNextResults != nil {
+		return *meta.HasNextResults
+	}
+	return itemCount >= pageSize
+}
@@ -0,0 +1,33 @@
+package fleetapi
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// The option structs leave filters unset with empty strings and nil pointers.
+// These helpers add only the filters which are set.
+
+func setString(params url.Values, key, value string) {
+	if value != "" {
+		params.Set(key, value)
+	}
+}
+
+func setUint(params url.Values, key string, value *uint) {
+	if value != nil {
+		params.Set(key, strconv.FormatUint(uint64(*value), 10))
+	}
+}
+
+func setInt(params url.Values, key string, value *int) {
+	if value != nil {
+		params.Set(key, strconv.Itoa(*value))
+	}
+}
+
+func setBool(params url.Values, key string, value *bool) {
+	if value != nil {
+		params.Set(key, strconv.FormatBool(*value))
+	}
+}
@@ -0,0 +1,75 @@
+package fleetapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// Policy represents a FleetDM policy.
+// Refer to: https://fleetdm.com/docs/rest-api/rest-api#policy-object
+// And: https://fleetdm.com/docs/rest-api/rest-api#get-a-policy
+type Policy struct {
+	ID                    uint      `json:"id"`
+	CreatedAt             FleetTime `json:"created_at"`
+	UpdatedAt             FleetTime `json:"updated_at"`
+	Name                  string    `json:"name"`
+	Query                 string    `json:"query"` // This is the actual osquery query text
+	Description           string    `json:"description"`
+	AuthorID              *uint     `json:"author_id"` // Pointer as it can be null
+	AuthorName            string    `json:"author_name"`
+	AuthorEmail           string    `json:"author_email"`
+	TeamID                *uint     `json:"team_id"`                 // Null if global policy
+	Resolution            string    `json:"resolution"`              // Instructions for failing hosts
+	Platform              string    `json:"platform"`                // e.g., "windows", "linux", "darwin", "" for all
+	PassingHostCount      int       `json:"passing_host_count"`      // Number of hosts passing the policy
+	FailingHostCount      int       `json:"failing_host_count"`      // Number of hosts failing the policy
+	Critical              bool      `json:"critical"`                // Whether the policy is critical (introduced in Fleet 4.41)
+	CalendarEventsEnabled bool      `json:"calendar_events_enabled"` // Whether calendar events are enabled for this policy (Fleet 4.44+)
+}
+
+// ListPoliciesResponse is the structure for the list policies API response.
+// Assuming `GET /api/v1/fleet/global/policies` returns `{"policies": [...]}`
+type ListPoliciesResponse struct {
+	Policies []Policy `json:"policies"`
+}
+
+// GetPolicyResponse is the structure for the get policy API response.
+// Assuming `GET /api/v1/fleet/global/policies/{id}` returns `{"policy": {...}}`
+type GetPolicyResponse struct {
+	Policy Policy `json:"policy"`
+}
+
+// ListPoliciesOptions selects global or team policies.
+type ListPoliciesOptions struct {
+	ListOptions
+	// TeamID lists the team's policies from /teams/:id/policies instead of the
+	// global policies. Fleet Premium only.
+	TeamID *uint
+	// Query and MergeInherited are only supported for team policies and are
+	// ignored without TeamID
+	Query          string
+	MergeInherited *bool
+}
+
+// ListPolicies lists the global or team policies until fn returns false.
+func (c *FleetDMClient) ListPolicies(ctx context.Context, opts ListPoliciesOptions, fn func(policy Policy) bool) error {
+	endpoint := "global/policies"
+	params := url.Values{}
+	if opts.TeamID != nil {
+		endpoint = fmt.Sprintf("teams/%d/policies", *opts.TeamID)
+		c.log(ctx).Debug("FleetDMClient.ListPolicies", "using_team_endpoint", endpoint)
+		setString(params, "query", opts.Query)
+		setBool(params, "merge_inherited", opts.MergeInherited)
+	}
+
+	pages := paginator[Policy]{
+		Name:     "FleetDMClient.ListPolicies",
+		Endpoint: endpoint,
+		Params:   params,
+		ItemsKey: "policies",
+		Mode:     paginateByCount,
+		PageSize: 50,
+	}
+	return pages.walk(ctx, c, opts.ListOptions, fn)
+}
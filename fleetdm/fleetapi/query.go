@@ -0,0 +1,85 @@
+package fleetapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// QuerySaved represents a saved query in FleetDM.
+// Refer to: https://fleetdm.com/docs/rest-api/rest-api#saved-query-object
+type QuerySaved struct {
+	ID                 uint            `json:"id"`
+	CreatedAt          time.Time       `json:"created_at"`
+	UpdatedAt          time.Time       `json:"updated_at"`
+	Name               string          `json:"name"`
+	Description        string          `json:"description"`
+	Query              string          `json:"query"` // The actual SQL query
+	AuthorID           *uint           `json:"author_id"`
+	AuthorName         string          `json:"author_name"`
+	AuthorEmail        string          `json:"author_email"`
+	ObserverCanRun     bool            `json:"observer_can_run"` // Whether observers can run this query
+	TeamID             *uint           `json:"team_id"`          // Null if global
+	AutomationsEnabled bool            `json:"automations_enabled"`
+	Interval           *uint           `json:"interval"` // For scheduled queries, in seconds
+	Platform           *string         `json:"platform"` // Comma-separated list or empty for all
+	MinOsqueryVersion  *string         `json:"min_osquery_version"`
+	Logging            *string         `json:"logging"` // "snapshot", "differential", "differential_ignore_removals"
+	Stats              json.RawMessage `json:"stats"`   // Performance statistics, complex object
+	Packs              []QueryPack     `json:"packs"`   // Packs this query belongs to (available on GET /queries/{id})
+}
+
+// QueryPack minimal info for a pack a query belongs to.
+type QueryPack struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"` // e.g. "global", "team"
+}
+
+// ListQueriesResponse is the structure for the list queries API response.
+// `GET /api/v1/fleet/queries` returns `{"queries": [...]}`
+type ListQueriesResponse struct {
+	Queries []QuerySaved `json:"queries"`
+	// Meta  struct { // If pagination meta is introduced
+	// 	HasNextResults bool `json:"has_next_results"`
+	// } `json:"meta"`
+}
+
+// GetQueryResponse is the structure for the get query API response.
+// `GET /api/v1/fleet/queries/{id}` returns `{"query": {...}}`
+type GetQueryResponse struct {
+	Query QuerySaved `json:"query"`
+}
+
+// ListQueriesOptions filters GET /api/v1/fleet/queries.
+type ListQueriesOptions struct {
+	ListOptions
+	Query          string // Matches the query name
+	TeamID         *uint  // Fleet Premium only
+	Platform       string // e.g. "darwin"
+	MergeInherited *bool  // Include global queries inherited by TeamID. Fleet Premium only.
+}
+
+// ListQueries lists the saved queries until fn returns false.
+//
+// The list endpoint might not include packs, which GET /queries/:id returns, so
+// Packs is empty here.
+func (c *FleetDMClient) ListQueries(ctx context.Context, opts ListQueriesOptions, fn func(query QuerySaved) bool) error {
+	params := url.Values{}
+	setString(params, "query", opts.Query)
+	setUint(params, "team_id", opts.TeamID)
+	setString(params, "platform", opts.Platform)
+	setBool(params, "merge_inherited", opts.MergeInherited)
+	// TODO: Support order_key and order_direction
+
+	pages := paginator[QuerySaved]{
+		Name:     "FleetDMClient.ListQueries",
+		Endpoint: "queries",
+		Params:   params,
+		ItemsKey: "queries",
+		Mode:     paginateByCount,
+		PageSize: 50, // API default is 20, max 100
+	}
+	return pages.walk(ctx, c, opts.ListOptions, fn)
+}
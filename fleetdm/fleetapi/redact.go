@@ -0,0 +1,212 @@
+package fleetapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces sensitive values in logs and error messages.
+const redacted = "<redacted>"
+
+// sensitiveNames are JSON keys and query params whose values are never logged or
+// returned in errors, e.g. enroll secrets in Team.Secrets, disk encryption keys
+// from hosts/:id/encryption_key, and agent, session and API tokens.
+var sensitiveNames = map[string]bool{
+	"secret":                  true,
+	"secrets":                 true,
+	"enroll_secret":           true,
+	"enroll_secrets":          true,
+	"password":                true,
+	"token":                   true,
+	"api_token":               true,
+	"session_token":           true,
+	"access_token":            true,
+	"refresh_token":           true,
+	"node_key":                true,
+	"orbit_node_key":          true,
+	"key":                     true,
+	"encryption_key":          true,
+	"disk_encryption_key":     true,
+	"recovery_key":            true,
+	"recovery_lock_password":  true,
+	"private_key":             true,
+	"client_secret":           true,
+	"authorization":           true,
+	"challenge":               true,
+	"scep_challenge":          true,
+	"apns_key":                true,
+	"abm_token":               true,
+	"vpp_token":               true,
+	"unlock_pin":              true,
+	"mdm_enrollment_password": true,
+}
+
+// notSensitiveNames look like secrets but are safe to log.
+var notSensitiveNames = map[string]bool{
+	"order_key": true,
+}
+
+// isSensitiveName reports whether values of the JSON key or query param name must
+// be masked. Besides the known names, anything ending in _secret, _token, _key or
+// _password is masked.
+func isSensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	if notSensitiveNames[name] {
+		return false
+	}
+	if sensitiveNames[name] {
+		return true
+	}
+	for _, suffix := range []string{"_secret", "_secrets", "_token", "_key", "_password"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactJSON masks the values of sensitive keys at any depth of a JSON body. A
+// body which is not valid JSON, such as a truncated one, has its sensitive string
+// values masked by pattern instead.
+func redactJSON(body []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return redactJSONText(body)
+	}
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(redactValue(value)); err != nil {
+		return redactJSONText(body)
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n"))
+}
+
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if isSensitiveName(key) && item != nil {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(item)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	}
+	return value
+}
+
+// redactJSONStrings masks the string values of sensitive keys at any depth of a
+// JSON body, keeping its structure so it still decodes into the API types, e.g.
+// each secret in Team.Secrets is masked but its created_at and team_id are kept.
+// A body which is not valid JSON is masked by pattern, like redactJSON.
+func redactJSONStrings(body []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return redactJSONText(body)
+	}
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(redactStrings(value, false)); err != nil {
+		return redactJSONText(body)
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n"))
+}
+
+// redactStrings masks the strings in value which belong to a sensitive key,
+// directly or as elements of its array.
+func redactStrings(value any, sensitive bool) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = redactStrings(item, isSensitiveName(key))
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactStrings(item, sensitive)
+		}
+	case string:
+		if sensitive {
+			return redacted
+		}
+	}
+	return value
+}
+
+// jsonStringField matches a "key": "value" pair in JSON text.
+var jsonStringField = regexp.MustCompile(`"([^"\\]+)"(\s*:\s*)"(?:[^"\\]|\\.)*("|$)`)
+
+func redactJSONText(body []byte) []byte {
+	return jsonStringField.ReplaceAllFunc(body, func(match []byte) []byte {
+		parts := jsonStringField.FindSubmatch(match)
+		if !isSensitiveName(string(parts[1])) {
+			return match
+		}
+		return []byte(`"` + string(parts[1]) + `"` + string(parts[2]) + `"` + redacted + `"`)
+	})
+}
+
+// redactURL masks sensitive query params and any password in a URL, for logs and
+// error messages.
+func redactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	if _, hasPassword := parsed.User.Password(); hasPassword {
+		parsed.User = url.UserPassword(parsed.User.Username(), redacted)
+	}
+	if parsed.RawQuery != "" {
+		parsed.RawQuery = redactQuery(parsed.Query())
+	}
+	return parsed.String()
+}
+
+// redactQuery encodes query params with sensitive values masked.
+func redactQuery(params url.Values) string {
+	masked := make(url.Values, len(params))
+	for name, values := range params {
+		if isSensitiveName(name) {
+			values = []string{redacted}
+		}
+		masked[name] = values
+	}
+	// Keep the marker readable rather than percent-encoded
+	return strings.ReplaceAll(masked.Encode(), url.QueryEscape(redacted), redacted)
+}
+
+// newDebugLogBodies returns the endpoint routes of the debug_log_bodies option,
+// normalised like the telemetry routes, e.g. "hosts/12" becomes "hosts/:id".
+func newDebugLogBodies(config Config) (map[string]bool, error) {
+	routes := make(map[string]bool, len(config.DebugLogBodies))
+	for _, endpoint := range config.DebugLogBodies {
+		if strings.TrimSpace(endpoint) == "" {
+			return nil, errors.New("debug_log_bodies must not contain an empty endpoint")
+		}
+		if endpoint == "*" {
+			routes[endpoint] = true
+			continue
+		}
+		routes[endpointRoute(endpoint)] = true
+	}
+	return routes, nil
+}
+
+// logsBodies reports whether full, unredacted response bodies from endpoint are
+// logged at debug level.
+func (c *FleetDMClient) logsBodies(endpoint string) bool {
+	return c.debugLogBodies["*"] || c.debugLogBodies[endpointRoute(endpoint)]
+}
@@ -0,0 +1,294 @@
+package fleetapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// countsProbeInterval is how long a probed counts_updated_at is trusted before
+// the server is asked again. Fleet refreshes the counts at most hourly.
+const countsProbeInterval = time.Minute
+
+// countsUpdatedAtEndpoints return a counts_updated_at timestamp which changes
+// whenever Fleet recomputes the software inventory and its vulnerabilities.
+var countsUpdatedAtEndpoints = map[string]bool{
+	"software/versions": true,
+	"software/titles":   true,
+	"os_versions":       true,
+}
+
+// cacheMode is stored in a query context to change how it uses the response cache.
+type cacheMode int
+
+const (
+	// cacheRefresh skips cached responses but stores fresh ones, see WithCacheRefresh
+	cacheRefresh cacheMode = iota + 1
+	// cacheOff neither reads nor writes the cache, for counts_updated_at probes
+	cacheOff
+)
+
+type cacheModeKey struct{}
+
+func withCacheMode(ctx context.Context, mode cacheMode) context.Context {
+	return context.WithValue(ctx, cacheModeKey{}, mode)
+}
+
+func cacheModeFrom(ctx context.Context) cacheMode {
+	mode, _ := ctx.Value(cacheModeKey{}).(cacheMode)
+	return mode
+}
+
+// WithCacheRefresh returns ctx marked to skip cached responses for the API calls
+// made with it, while still caching the fresh ones.
+func WithCacheRefresh(ctx context.Context) context.Context {
+	return withCacheMode(ctx, cacheRefresh)
+}
+
+// cacheFamily maps an endpoint to the family whose *_cache_ttl applies. It
+// follows endpointFamily, except that os_versions, which Fleet refreshes along
+// with the software inventory, shares the software TTL.
+func cacheFamily(endpoint string) string {
+	if strings.Trim(endpoint, "/") == "os_versions" {
+		return EndpointFamilySoftware
+	}
+	return endpointFamily(endpoint)
+}
+
+// responseCache stores successful API responses as files in cache_dir, keyed by
+// the credentials and the full request URL, so repeated dashboard queries survive
+// Steampipe restarts. Clients with different tokens, which may be scoped to
+// different teams, never read each other's entries.
+// Entries expire after their family's TTL. Entries for the software and OS
+// version endpoints are also dropped once the server's counts_updated_at moves on.
+type responseCache struct {
+	dir  string
+	ttls map[string]time.Duration // keyed by cache family
+	// scope is a hash of the credential identity, part of every entry's key
+	scope string
+
+	mutex  sync.Mutex
+	probes map[string]countsProbe // keyed by endpoint
+}
+
+// countsProbe is the latest counts_updated_at seen for an endpoint.
+type countsProbe struct {
+	value     string
+	checkedAt time.Time
+}
+
+// cacheEntry is the file format of a cached response.
+type cacheEntry struct {
+	URL             string          `json:"url"`
+	StoredAt        time.Time       `json:"stored_at"`
+	CountsUpdatedAt string          `json:"counts_updated_at,omitempty"`
+	Body            json.RawMessage `json:"body"`
+}
+
+// newResponseCache builds the cache for the cache_dir and *_cache_ttl options,
+// scoped to the given credential identity. It returns nil when caching is not
+// configured.
+func newResponseCache(config Config, identity string) (*responseCache, error) {
+	limits := map[string]*int{
+		EndpointFamilyHosts:      config.HostsCacheTTL,
+		EndpointFamilyHostDetail: config.HostDetailCacheTTL,
+		EndpointFamilySoftware:   config.SoftwareCacheTTL,
+		EndpointFamilyActivities: config.ActivitiesCacheTTL,
+	}
+	ttls := make(map[string]time.Duration)
+	for family, ttl := range limits {
+		if ttl == nil {
+			continue
+		}
+		if *ttl < 0 {
+			return nil, fmt.Errorf("%s_cache_ttl must be zero or greater", family)
+		}
+		if *ttl > 0 {
+			ttls[family] = time.Duration(*ttl) * time.Second
+		}
+	}
+
+	dir := ""
+	if config.CacheDir != nil {
+		dir = *config.CacheDir
+	}
+	if dir == "" {
+		if len(ttls) > 0 {
+			return nil, errors.New("the *_cache_ttl options require cache_dir to be set")
+		}
+		return nil, nil
+	}
+	if len(ttls) == 0 {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error creating cache_dir '%s': %w", dir, err)
+	}
+	scope := sha256.Sum256([]byte(identity))
+	return &responseCache{dir: dir, ttls: ttls, scope: hex.EncodeToString(scope[:]), probes: make(map[string]countsProbe)}, nil
+}
+
+// ttl returns how long responses from endpoint are cached, zero if they are not.
+func (rc *responseCache) ttl(endpoint string) time.Duration {
+	if rc == nil {
+		return 0
+	}
+	return rc.ttls[cacheFamily(endpoint)]
+}
+
+// path returns the file a response for requestURL is cached in.
+func (rc *responseCache) path(requestURL string) string {
+	sum := sha256.Sum256([]byte(rc.scope + " " + requestURL))
+	return filepath.Join(rc.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// load returns the cached entry for requestURL if it is younger than ttl.
+func (rc *responseCache) load(requestURL string, ttl time.Duration) (*cacheEntry, bool) {
+	path := rc.path(requestURL)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != requestURL {
+		return nil, false
+	}
+	if time.Since(entry.StoredAt) > ttl {
+		_ = os.Remove(path)
+		return nil, false
+	}
+	return &entry, true
+}
+
+// store writes a response body to the cache. The file is replaced atomically, so
+// concurrent queries never read a partial entry.
+func (rc *responseCache) store(endpoint, requestURL string, body []byte) error {
+	entry := cacheEntry{URL: requestURL, StoredAt: time.Now(), Body: body}
+	if countsUpdatedAtEndpoints[strings.Trim(endpoint, "/")] {
+		entry.CountsUpdatedAt = countsUpdatedAt(body)
+		rc.remember(endpoint, entry.CountsUpdatedAt)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(rc.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), rc.path(requestURL))
+}
+
+// remember records the counts_updated_at just seen for endpoint.
+func (rc *responseCache) remember(endpoint, value string) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.probes[strings.Trim(endpoint, "/")] = countsProbe{value: value, checkedAt: time.Now()}
+}
+
+// recentProbe returns the counts_updated_at seen for endpoint within countsProbeInterval.
+func (rc *responseCache) recentProbe(endpoint string) (string, bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	probe, ok := rc.probes[strings.Trim(endpoint, "/")]
+	if !ok || time.Since(probe.checkedAt) > countsProbeInterval {
+		return "", false
+	}
+	return probe.value, true
+}
+
+// countsUpdatedAt extracts the top-level counts_updated_at from a response body.
+func countsUpdatedAt(body []byte) string {
+	var fields struct {
+		CountsUpdatedAt json.RawMessage `json:"counts_updated_at"`
+	}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return ""
+	}
+	return string(fields.CountsUpdatedAt)
+}
+
+// cachedResponse returns the cached body for a request, if the cache holds a
+// fresh entry for it.
+func (c *FleetDMClient) cachedResponse(ctx context.Context, endpoint, requestURL string, ttl time.Duration) ([]byte, bool) {
+	entry, ok := c.cache.load(requestURL, ttl)
+	if !ok {
+		return nil, false
+	}
+	if countsUpdatedAtEndpoints[strings.Trim(endpoint, "/")] {
+		current, err := c.currentCountsUpdatedAt(ctx, endpoint)
+		if err != nil {
+			c.log(ctx).Warn("FleetDMClient.cachedResponse", "counts_probe_error", err, "endpoint", endpoint)
+			return nil, false
+		}
+		if current != entry.CountsUpdatedAt {
+			c.log(ctx).Debug("FleetDMClient.cachedResponse", "counts_updated_at_changed", true, "endpoint", endpoint, "cached", entry.CountsUpdatedAt, "current", current)
+			return nil, false
+		}
+	}
+	c.log(ctx).Debug("FleetDMClient.cachedResponse", "cache_hit", redactURL(requestURL), "age", time.Since(entry.StoredAt).Round(time.Second).String())
+	return entry.Body, true
+}
+
+// currentCountsUpdatedAt asks the server for the endpoint's counts_updated_at
+// with a one-item page, unless it was seen within countsProbeInterval.
+func (c *FleetDMClient) currentCountsUpdatedAt(ctx context.Context, endpoint string) (string, error) {
+	if value, ok := c.cache.recentProbe(endpoint); ok {
+		return value, nil
+	}
+	params := url.Values{}
+	params.Set("page", "0")
+	params.Set("per_page", "1")
+	var fields struct {
+		CountsUpdatedAt json.RawMessage `json:"counts_updated_at"`
+	}
+	if _, err := c.Get(withCacheMode(ctx, cacheOff), endpoint, params, &fields); err != nil {
+		return "", err
+	}
+	value := string(fields.CountsUpdatedAt)
+	c.cache.remember(endpoint, value)
+	return value, nil
+}
+
+// cachingBody passes a response body through to the reader while keeping a copy,
+// which is stored once the reader has consumed the whole body.
+type cachingBody struct {
+	io.Reader
+	io.Closer
+	copy bytes.Buffer
+}
+
+func newCachingBody(body io.ReadCloser) *cachingBody {
+	cb := &cachingBody{Closer: body}
+	cb.Reader = io.TeeReader(body, &cb.copy)
+	return cb
+}
+
+// finish reads whatever the reader left, such as trailing whitespace, and returns
+// the complete body.
+func (cb *cachingBody) finish() ([]byte, error) {
+	if _, err := io.Copy(io.Discard, cb.Reader); err != nil {
+		return nil, err
+	}
+	return cb.copy.Bytes(), nil
+}
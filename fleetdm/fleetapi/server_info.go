@@ -0,0 +1,132 @@
+package fleetapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// License tiers reported by Fleet in the `license.tier` field of /config.
+const (
+	licenseTierFree    = "free"
+	licenseTierPremium = "premium"
+)
+
+// ServerInfo describes the Fleet server behind a connection, combining the build
+// information from GET /version with the license from GET /config.
+type ServerInfo struct {
+	ServerURL           string     `json:"server_url"`
+	Version             string     `json:"version"`
+	Branch              string     `json:"branch"`
+	Revision            string     `json:"revision"`
+	GoVersion           string     `json:"go_version"`
+	BuildDate           string     `json:"build_date"`
+	BuildUser           string     `json:"build_user"`
+	LicenseTier         string     `json:"license_tier"`
+	LicenseOrganization string     `json:"license_organization"`
+	LicenseDeviceCount  int        `json:"license_device_count"`
+	LicenseExpiration   *FleetTime `json:"license_expiration"`
+	OrgName             string     `json:"org_name"`
+}
+
+// versionResponse is the body returned by GET /api/v1/fleet/version.
+type versionResponse struct {
+	Version   string `json:"version"`
+	Branch    string `json:"branch"`
+	Revision  string `json:"revision"`
+	GoVersion string `json:"go_version"`
+	BuildDate string `json:"build_date"`
+	BuildUser string `json:"build_user"`
+}
+
+// configResponse holds the parts of GET /api/v1/fleet/config used for feature detection.
+type configResponse struct {
+	OrgInfo struct {
+		OrgName string `json:"org_name"`
+	} `json:"org_info"`
+	License struct {
+		Tier         string     `json:"tier"`
+		Organization string     `json:"organization"`
+		DeviceCount  int        `json:"device_count"`
+		Expiration   *FleetTime `json:"expiration"`
+	} `json:"license"`
+}
+
+// IsPremium reports whether the server runs with a Fleet Premium license.
+func (s *ServerInfo) IsPremium() bool {
+	return s.LicenseTier == licenseTierPremium
+}
+
+// AtLeast reports whether the server version is minVersion or later. Development
+// and otherwise unparsable versions are assumed to support every feature.
+func (s *ServerInfo) AtLeast(minVersion string) bool {
+	have, ok := parseFleetVersion(s.Version)
+	if !ok {
+		return true
+	}
+	want, ok := parseFleetVersion(minVersion)
+	if !ok {
+		return true
+	}
+	for i := range have {
+		if have[i] != want[i] {
+			return have[i] > want[i]
+		}
+	}
+	return true
+}
+
+// parseFleetVersion parses versions such as "4.62.1" or "v4.62.1-rc.2" into
+// major, minor and patch numbers. "0.0.0" development builds are not parsed.
+func parseFleetVersion(version string) ([3]int, bool) {
+	var parsed [3]int
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return parsed, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return parsed, false
+		}
+		parsed[i] = n
+	}
+	return parsed, parsed != [3]int{}
+}
+
+// GetServerInfo detects the version and license tier of the server from GET
+// /version and GET /config. Servers which report no license run the free tier.
+func (c *FleetDMClient) GetServerInfo(ctx context.Context) (*ServerInfo, error) {
+	var version versionResponse
+	if _, err := c.Get(ctx, "version", nil, &version); err != nil {
+		return nil, fmt.Errorf("error detecting Fleet server version: %w", err)
+	}
+	var config configResponse
+	if _, err := c.Get(ctx, "config", nil, &config); err != nil {
+		return nil, fmt.Errorf("error detecting Fleet license tier: %w", err)
+	}
+
+	info := &ServerInfo{
+		ServerURL:           strings.TrimSuffix(c.BaseURL, "/api/v1/fleet/"),
+		Version:             version.Version,
+		Branch:              version.Branch,
+		Revision:            version.Revision,
+		GoVersion:           version.GoVersion,
+		BuildDate:           version.BuildDate,
+		BuildUser:           version.BuildUser,
+		LicenseTier:         config.License.Tier,
+		LicenseOrganization: config.License.Organization,
+		LicenseDeviceCount:  config.License.DeviceCount,
+		LicenseExpiration:   config.License.Expiration,
+		OrgName:             config.OrgInfo.OrgName,
+	}
+	if info.LicenseTier == "" {
+		info.LicenseTier = licenseTierFree
+	}
+	return info, nil
+}
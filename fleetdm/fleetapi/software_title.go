@@ -0,0 +1,94 @@
+package fleetapi
+
+import (
+	"context"
+	"net/url"
+)
+
+// SoftwareTitleVersion represents a version entry within a software title.
+type SoftwareTitleVersion struct {
+	ID              uint     `json:"id"`
+	Version         string   `json:"version"`
+	Vulnerabilities []string `json:"vulnerabilities"` // List of CVE strings
+	HostsCount      *uint    `json:"hosts_count,omitempty"`
+}
+
+// SoftwareTitle represents a software title in FleetDM.
+// Refer to: https://fleetdm.com/docs/rest-api/rest-api#list-software
+type SoftwareTitle struct {
+	ID               uint                   `json:"id"`
+	Name             string                 `json:"name"`
+	DisplayName      string                 `json:"display_name"`
+	IconURL          *string                `json:"icon_url"`
+	Source           string                 `json:"source"`
+	ExtensionFor     string                 `json:"extension_for"`
+	Browser          string                 `json:"browser"`
+	HostsCount       uint                   `json:"hosts_count"`
+	VersionsCount    uint                   `json:"versions_count"`
+	Versions         []SoftwareTitleVersion `json:"versions"`
+	SoftwarePackage  interface{}            `json:"software_package"`
+	AppStoreApp      interface{}            `json:"app_store_app"`
+	BundleIdentifier *string                `json:"bundle_identifier"`
+	CountsUpdatedAt  *FleetTime             `json:"counts_updated_at"`
+}
+
+// ListSoftwareTitlesResponse is the expected structure for the list software titles API call.
+type ListSoftwareTitlesResponse struct {
+	SoftwareTitles []SoftwareTitle `json:"software_titles"`
+	Meta           struct {
+		HasNextResults     bool `json:"has_next_results"`
+		HasPreviousResults bool `json:"has_previous_results"`
+	} `json:"meta"`
+	Count           int        `json:"count"`
+	CountsUpdatedAt *FleetTime `json:"counts_updated_at"`
+}
+
+// ListSoftwareTitlesOptions filters GET /api/v1/fleet/software/titles.
+type ListSoftwareTitlesOptions struct {
+	ListOptions
+	Vulnerable                 *bool
+	TeamID                     *uint
+	AvailableForInstall        *bool
+	Query                      string // Matches the title name and CVE
+	SelfService                *bool
+	PackagesOnly               *bool // Fleet Premium only
+	MinCVSSScore               *int  // Fleet Premium only, implies Vulnerable
+	MaxCVSSScore               *int  // Fleet Premium only, implies Vulnerable
+	Exploit                    *bool // Fleet Premium only, implies Vulnerable
+	Platform                   string
+	ExcludeFleetMaintainedApps *bool
+}
+
+// ListSoftwareTitles lists the software titles, most installed first, until fn
+// returns false.
+func (c *FleetDMClient) ListSoftwareTitles(ctx context.Context, opts ListSoftwareTitlesOptions, fn func(title SoftwareTitle) bool) error {
+	params := url.Values{}
+	params.Add("order_key", "hosts_count")
+	params.Add("order_direction", "desc")
+	setBool(params, "vulnerable", opts.Vulnerable)
+	setUint(params, "team_id", opts.TeamID)
+	setBool(params, "available_for_install", opts.AvailableForInstall)
+	setString(params, "query", opts.Query)
+	setBool(params, "self_service", opts.SelfService)
+	setBool(params, "packages_only", opts.PackagesOnly)
+	// The API requires vulnerable=true when using min_cvss_score, max_cvss_score, or exploit.
+	// Auto-set vulnerable=true if any of these are specified and Vulnerable was not explicitly set.
+	if opts.Vulnerable == nil && (opts.MinCVSSScore != nil || opts.MaxCVSSScore != nil || opts.Exploit != nil) {
+		params.Set("vulnerable", "true")
+	}
+	setInt(params, "min_cvss_score", opts.MinCVSSScore)
+	setInt(params, "max_cvss_score", opts.MaxCVSSScore)
+	setBool(params, "exploit", opts.Exploit)
+	setString(params, "platform", opts.Platform)
+	setBool(params, "exclude_fleet_maintained_apps", opts.ExcludeFleetMaintainedApps)
+
+	pages := paginator[SoftwareTitle]{
+		Name:     "FleetDMClient.ListSoftwareTitles",
+		Endpoint: "software/titles", // Endpoint is /api/v1/fleet/software/titles
+		Params:   params,
+		ItemsKey: "software_titles",
+		Mode:     paginateByMeta,
+		PageSize: 10000,
+	}
+	return pages.walk(ctx, c, opts.ListOptions, fn)
+}
@@ -0,0 +1,122 @@
+package fleetapi
+
+import (
+	"context"
+	"net/url"
+)
+
+// SoftwareVulnerability represents a vulnerability associated with a software item.
+type SoftwareVulnerability struct {
+	CVE                           string     `json:"cve"`
+	DetailsLink                   string     `json:"details_link"`
+	CVSSScore                     *float64   `json:"cvss_score,omitempty"`                       // Common Vulnerability Scoring System
+	EPSSProbability               *float64   `json:"epss_probability,omitempty"`                 // Exploit Prediction Scoring System
+	CISAKnownExploit              *bool      `json:"cisa_known_exploit,omitempty"`               // CISA Known Exploited Vulnerabilities Catalog
+	CVEPublished                  *FleetTime `json:"cve_published,omitempty"`                    // Date CVE was published
+	ResolvedInVersion             *string    `json:"resolved_in_version,omitempty"`              // Version the vulnerability is resolved in
+	CurrentlyExploited            *bool      `json:"currently_exploited,omitempty"`              // Premium feature: From Recorded Future
+	Exploitability7Day            *int       `json:"exploitability_7_day,omitempty"`             // Premium feature
+	Exploitability30Day           *int       `json:"exploitability_30_day,omitempty"`            // Premium feature
+	Exploitability60Day           *int       `json:"exploitability_60_day,omitempty"`            // Premium feature
+	Exploitability90Day           *int       `json:"exploitability_90_day,omitempty"`            // Premium feature
+	ExploitedActivity7Day         *int       `json:"exploited_activity_7_day,omitempty"`         // Premium feature
+	ExploitedActivity30Day        *int       `json:"exploited_activity_30_day,omitempty"`        // Premium feature
+	ExploitedActivity60Day        *int       `json:"exploited_activity_60_day,omitempty"`        // Premium feature
+	ExploitedActivity90Day        *int       `json:"exploited_activity_90_day,omitempty"`        // Premium feature
+	ExploitedMalware7Day          *int       `json:"exploited_malware_7_day,omitempty"`          // Premium feature
+	ExploitedMalware30Day         *int       `json:"exploited_malware_30_day,omitempty"`         // Premium feature
+	ExploitedMalware60Day         *int       `json:"exploited_malware_60_day,omitempty"`         // Premium feature
+	ExploitedMalware90Day         *int       `json:"exploited_malware_90_day,omitempty"`         // Premium feature
+	ExploitedNetwork7Day          *int       `json:"exploited_network_7_day,omitempty"`          // Premium feature
+	ExploitedNetwork30Day         *int       `json:"exploited_network_30_day,omitempty"`         // Premium feature
+	ExploitedNetwork60Day         *int       `json:"exploited_network_60_day,omitempty"`         // Premium feature
+	ExploitedNetwork90Day         *int       `json:"exploited_network_90_day,omitempty"`         // Premium feature
+	ExploitedPublic7Day           *int       `json:"exploited_public_7_day,omitempty"`           // Premium feature
+	ExploitedPublic30Day          *int       `json:"exploited_public_30_day,omitempty"`          // Premium feature
+	ExploitedPublic60Day          *int       `json:"exploited_public_60_day,omitempty"`          // Premium feature
+	ExploitedPublic90Day          *int       `json:"exploited_public_90_day,omitempty"`          // Premium feature
+	ExploitedRansomware7Day       *int       `json:"exploited_ransomware_7_day,omitempty"`       // Premium feature
+	ExploitedRansomware30Day      *int       `json:"exploited_ransomware_30_day,omitempty"`      // Premium feature
+	ExploitedRansomware60Day      *int       `json:"exploited_ransomware_60_day,omitempty"`      // Premium feature
+	ExploitedRansomware90Day      *int       `json:"exploited_ransomware_90_day,omitempty"`      // Premium feature
+	ExploitedRemote7Day           *int       `json:"exploited_remote_7_day,omitempty"`           // Premium feature
+	ExploitedRemote30Day          *int       `json:"exploited_remote_30_day,omitempty"`          // Premium feature
+	ExploitedRemote60Day          *int       `json:"exploited_remote_60_day,omitempty"`          // Premium feature
+	ExploitedRemote90Day          *int       `json:"exploited_remote_90_day,omitempty"`          // Premium feature
+	ExploitedUnauthenticated7Day  *int       `json:"exploited_unauthenticated_7_day,omitempty"`  // Premium feature
+	ExploitedUnauthenticated30Day *int       `json:"exploited_unauthenticated_30_day,omitempty"` // Premium feature
+	ExploitedUnauthenticated60Day *int       `json:"exploited_unauthenticated_60_day,omitempty"` // Premium feature
+	ExploitedUnauthenticated90Day *int       `json:"exploited_unauthenticated_90_day,omitempty"` // Premium feature
+}
+
+// Software represents a software item in FleetDM.
+// Refer to: https://fleetdm.com/docs/rest-api/rest-api#software-item
+type Software struct {
+	ID               uint                    `json:"id"`
+	Name             string                  `json:"name"`
+	Version          string                  `json:"version"`
+	Source           string                  `json:"source"`
+	ExtensionFor     *string                 `json:"extension_for"`     // For browser extensions - extension for which app
+	Browser          *string                 `json:"browser,omitempty"` // For browser extensions
+	Vendor           *string                 `json:"vendor,omitempty"`  // e.g., for RPMs
+	GeneratedCPE     string                  `json:"generated_cpe"`
+	BundleIdentifier *string                 `json:"bundle_identifier"` // macOS, iOS
+	HostCount        uint                    `json:"hosts_count"`       // Number of hosts with this software (note: API uses "hosts_count" plural)
+	Vulnerabilities  []SoftwareVulnerability `json:"vulnerabilities"`
+	UpgradeCode      *string                 `json:"upgrade_code"`           // Windows installer upgrade code
+	DisplayName      *string                 `json:"display_name"`           // Display name for the software
+	LastOpenedAt     *FleetTime              `json:"last_opened_at"`         // This is typically per-host, might be null or aggregated differently in the global software list
+	Release          *string                 `json:"release,omitempty"`      // e.g., for RPMs
+	Arch             *string                 `json:"arch,omitempty"`         // e.g., for RPMs
+	ExtensionID      *string                 `json:"extension_id,omitempty"` // For browser extensions
+}
+
+// ListSoftwareResponse is the expected structure for the list software API call.
+type ListSoftwareResponse struct {
+	Software []Software `json:"software"`
+	Meta     struct {
+		HasNextResults     bool   `json:"has_next_results"`
+		HasPreviousResults bool   `json:"has_previous_results"`
+		NextCursor         string `json:"next_cursor"`
+	} `json:"meta"`
+	Count int `json:"count"` // Total count of all software items matching the query
+}
+
+// ListSoftwareVersionsOptions filters GET /api/v1/fleet/software/versions.
+type ListSoftwareVersionsOptions struct {
+	ListOptions
+	Vulnerable   *bool
+	TeamID       *uint
+	Query        string // Matches the name, version and CVE
+	MinCVSSScore *int   // Fleet Premium only, implies Vulnerable
+	MaxCVSSScore *int   // Fleet Premium only, implies Vulnerable
+	Exploit      *bool  // Fleet Premium only, implies Vulnerable
+}
+
+// ListSoftwareVersions lists the software versions until fn returns false.
+func (c *FleetDMClient) ListSoftwareVersions(ctx context.Context, opts ListSoftwareVersionsOptions, fn func(software Software) bool) error {
+	params := url.Values{}
+	params.Add("order_key", "id")
+	params.Add("order_direction", "asc")
+	setBool(params, "vulnerable", opts.Vulnerable)
+	setUint(params, "team_id", opts.TeamID)
+	setString(params, "query", opts.Query)
+	// The API requires vulnerable=true when using min_cvss_score, max_cvss_score, or exploit.
+	// Auto-set vulnerable=true if any of these are specified and Vulnerable was not explicitly set.
+	if opts.Vulnerable == nil && (opts.MinCVSSScore != nil || opts.MaxCVSSScore != nil || opts.Exploit != nil) {
+		params.Set("vulnerable", "true")
+	}
+	setInt(params, "min_cvss_score", opts.MinCVSSScore)
+	setInt(params, "max_cvss_score", opts.MaxCVSSScore)
+	setBool(params, "exploit", opts.Exploit)
+
+	pages := paginator[Software]{
+		Name:     "FleetDMClient.ListSoftwareVersions",
+		Endpoint: "software/versions", // Endpoint is /api/v1/fleet/software/versions
+		Params:   params,
+		ItemsKey: "software",
+		Mode:     paginateByMeta,
+		PageSize: 10000, // This seems to have no limit and 100 was making it super slow, 1000 slow so let's go with 10000 🤠.
+	}
+	return pages.walk(ctx, c, opts.ListOptions, fn)
+}
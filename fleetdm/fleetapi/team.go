@@ -0,0 +1,78 @@
+package fleetapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+)
+
+// Team represents a FleetDM team.
+// Refer to: https://fleetdm.com/docs/rest-api/rest-api#team-object
+type Team struct {
+	ID           uint             `json:"id"`
+	CreatedAt    FleetTime        `json:"created_at"`
+	Name         string           `json:"name"`
+	Description  string           `json:"description"`
+	UserCount    int              `json:"user_count"`    // Calculated field, number of users in the team
+	HostCount    int              `json:"host_count"`    // Calculated field, number of hosts in the team
+	Secrets      []TeamSecret     `json:"secrets"`       // Agent enrollment secrets
+	Users        []TeamUser       `json:"users"`         // Users in the team with their roles
+	AgentOptions *json.RawMessage `json:"agent_options"` // Agent options for this team (can be complex JSON)
+	// TODO: Add other fields like 'policies_count', 'mdm', etc. if they become available directly on the team object
+	// Or consider hydrating them if they require separate API calls.
+}
+
+// TeamSecret represents an enrollment secret for a team.
+type TeamSecret struct {
+	Secret    string    `json:"secret"`
+	CreatedAt FleetTime `json:"created_at"`
+	TeamID    uint      `json:"team_id"` // This might be redundant if secrets are always nested under a team object
+}
+
+// TeamUser represents a user within a team and their role.
+// This is similar to UserTeam in the user table but might be structured slightly differently
+// in the /teams endpoint response if it includes more/less detail.
+// The API doc for "Get team" shows `users` array with `id`, `name`, `email`, `global_role`, `role`.
+type TeamUser struct {
+	ID         uint    `json:"id"`
+	Name       string  `json:"name"`
+	Email      string  `json:"email"`
+	GlobalRole *string `json:"global_role"` // User's global role
+	Role       string  `json:"role"`        // User's role within this specific team
+}
+
+// ListTeamsResponse is the structure for the list teams API response.
+// The API `GET /api/v1/fleet/teams` returns an array of teams directly.
+// For consistency, we'll use a wrapper, but the actual API might just be `[]Team`.
+// Update: The API doc for "List teams" (https://fleetdm.com/docs/rest-api/rest-api#list-all-teams)
+// shows a response like: { "teams": [ { ...team_object... } ] }
+type ListTeamsResponse struct {
+	Teams []Team `json:"teams"`
+	// Meta  struct { // If pagination meta is introduced for teams
+	// 	HasNextResults bool `json:"has_next_results"`
+	// } `json:"meta"`
+}
+
+// ListTeamsOptions filters GET /api/v1/fleet/teams.
+type ListTeamsOptions struct {
+	ListOptions
+	Query string // Matches the team name
+}
+
+// ListTeams lists the teams until fn returns false. Teams require Fleet Premium.
+// The listed teams do not include their users and secrets.
+func (c *FleetDMClient) ListTeams(ctx context.Context, opts ListTeamsOptions, fn func(team Team) bool) error {
+	params := url.Values{}
+	setString(params, "query", opts.Query)
+
+	// /teams does not specify a `meta.has_next_results`
+	pages := paginator[Team]{
+		Name:     "FleetDMClient.ListTeams",
+		Endpoint: "teams",
+		Params:   params,
+		ItemsKey: "teams",
+		Mode:     paginateByCount,
+		PageSize: 10000,
+	}
+	return pages.walk(ctx, c, opts.ListOptions, fn)
+}
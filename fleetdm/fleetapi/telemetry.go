@@ -0,0 +1,166 @@
+package fleetapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Fleet API calls are traced and measured through the global OpenTelemetry
+// providers. The Steampipe SDK installs them when STEAMPIPE_OTEL_LEVEL is set, so
+// the plugin's calls are exported to the same collector as the SDK's own spans.
+// Without providers, nothing is recorded.
+
+// telemetryScope names the client's tracer and meter. It matches the service name
+// the SDK exports the plugin's telemetry under.
+const telemetryScope = "steampipe-plugin-fleetdm"
+
+// apiMetrics are the instruments recorded for every Fleet API call.
+type apiMetrics struct {
+	requests metric.Int64Counter
+	duration metric.Float64Histogram
+	retries  metric.Int64Counter
+}
+
+// getAPIMetrics creates the instruments on first use. Instruments created before
+// the SDK installs its meter provider are forwarded to it.
+var getAPIMetrics = sync.OnceValue(func() *apiMetrics {
+	meter := otel.GetMeterProvider().Meter(telemetryScope)
+	m := &apiMetrics{}
+	var err error
+	if m.requests, err = meter.Int64Counter("fleetdm.api.requests",
+		metric.WithDescription("Fleet API calls, by endpoint, table and status code. Responses served from the response cache are included."),
+		metric.WithUnit("{request}")); err != nil {
+		otel.Handle(err)
+	}
+	if m.duration, err = meter.Float64Histogram("fleetdm.api.request.duration",
+		metric.WithDescription("Duration of Fleet API calls, including retries and reading the response."),
+		metric.WithUnit("s")); err != nil {
+		otel.Handle(err)
+	}
+	if m.retries, err = meter.Int64Counter("fleetdm.api.retries",
+		metric.WithDescription("Retries of Fleet API requests after a rate limit, gateway or network error, by the endpoint, table and final status code of the call."),
+		metric.WithUnit("{request}")); err != nil {
+		otel.Handle(err)
+	}
+	return m
+})
+
+type callLabelKey struct{}
+
+// WithLabel returns ctx labelled with the caller the API calls made with it are
+// for, e.g. a report or job name. The label is added to their spans and metrics,
+// as the fleetdm.table attribute, and selects the Config.LabelRequestTimeout
+// entry that applies.
+func WithLabel(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, callLabelKey{}, label)
+}
+
+func labelFromContext(ctx context.Context) string {
+	label, _ := ctx.Value(callLabelKey{}).(string)
+	return label
+}
+
+// endpointRoute replaces the ids in an endpoint with ":id", e.g. "hosts/12" becomes
+// "hosts/:id", so metrics are not split per host.
+func endpointRoute(endpoint string) string {
+	segments := strings.Split(strings.Trim(endpoint, "/"), "/")
+	for i, segment := range segments {
+		if _, err := strconv.ParseUint(segment, 10, 64); err == nil {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// apiCall is the span and measurements of one FleetDMClient.Get call.
+type apiCall struct {
+	span     trace.Span
+	start    time.Time
+	route    string
+	label    string
+	cacheHit bool
+	// notModified is set when a 304 let the call reuse a body kept in memory
+	notModified bool
+	retries     int
+}
+
+type apiCallKey struct{}
+
+// startAPICall starts the span for a call to endpoint. The page and cursor are
+// recorded so slow pages of a long listing can be told apart.
+func startAPICall(ctx context.Context, endpoint string, queryParams url.Values) (context.Context, *apiCall) {
+	call := &apiCall{
+		start: time.Now(),
+		route: endpointRoute(endpoint),
+		label: labelFromContext(ctx),
+	}
+	ctx, call.span = otel.Tracer(telemetryScope).Start(ctx, "FleetDMClient.Get ("+call.route+")")
+	ctx = context.WithValue(ctx, apiCallKey{}, call)
+	call.span.SetAttributes(
+		attribute.String("fleetdm.endpoint", call.route),
+		attribute.String("fleetdm.table", call.label),
+	)
+	for _, param := range []string{"page", "per_page", "after"} {
+		if value := queryParams.Get(param); value != "" {
+			call.span.SetAttributes(attribute.String("fleetdm."+param, value))
+		}
+	}
+	return ctx, call
+}
+
+// end records the outcome of the call and ends its span. resp is nil when the
+// request failed before a response arrived.
+func (call *apiCall) end(ctx context.Context, resp *http.Response, err error) {
+	duration := time.Since(call.start)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	if call.notModified {
+		status = http.StatusNotModified
+	}
+
+	call.span.SetAttributes(
+		attribute.Int("http.status_code", status),
+		attribute.Int64("fleetdm.duration_ms", duration.Milliseconds()),
+		attribute.Bool("fleetdm.cache_hit", call.cacheHit),
+		attribute.Int("fleetdm.retries", call.retries),
+	)
+	if err != nil && !errors.Is(err, errStopStream) {
+		call.span.RecordError(err)
+		call.span.SetStatus(codes.Error, err.Error())
+	}
+	call.span.End()
+
+	attributes := metric.WithAttributes(
+		attribute.String("endpoint", call.route),
+		attribute.String("table", call.label),
+		attribute.Int("status_code", status),
+		attribute.Bool("cache_hit", call.cacheHit),
+	)
+	metrics := getAPIMetrics()
+	metrics.requests.Add(ctx, 1, attributes)
+	metrics.duration.Record(ctx, duration.Seconds(), attributes)
+	if call.retries > 0 {
+		metrics.retries.Add(ctx, int64(call.retries), attributes)
+	}
+}
+
+// recordRetry counts a retried request against the API call in ctx, if any.
+func recordRetry(ctx context.Context) {
+	if call, ok := ctx.Value(apiCallKey{}).(*apiCall); ok {
+		call.retries++
+	}
+}
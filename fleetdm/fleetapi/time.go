@@ -0,0 +1,28 @@
+package fleetapi
+
+import "time"
+
+// FleetTime is a custom time type that handles empty strings in JSON unmarshalling.
+// The Fleet DM API may return empty strings for time fields, which causes
+// the standard time.Time JSON unmarshaller to fail.
+type FleetTime struct {
+	time.Time
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for FleetTime.
+func (ft *FleetTime) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" || s == `""` {
+		ft.Time = time.Time{}
+		return nil
+	}
+	return ft.Time.UnmarshalJSON(data)
+}
+
+// MarshalJSON implements the json.Marshaler interface for FleetTime.
+func (ft FleetTime) MarshalJSON() ([]byte, error) {
+	if ft.IsZero() {
+		return []byte("null"), nil
+	}
+	return ft.Time.MarshalJSON()
+}
@@ -0,0 +1,61 @@
+package fleetapi
+
+import (
+	"context"
+	"net/url"
+)
+
+// User represents a FleetDM user.
+// Refer to: https://fleetdm.com/docs/rest-api/rest-api#user-object
+type User struct {
+	ID                       uint       `json:"id"`
+	CreatedAt                FleetTime  `json:"created_at"`
+	UpdatedAt                FleetTime  `json:"updated_at"`
+	Name                     string     `json:"name"`
+	Email                    string     `json:"email"`
+	AdminForcedPasswordReset bool       `json:"admin_forced_password_reset"`
+	GravatarURL              string     `json:"gravatar_url"`
+	SSOEnabled               bool       `json:"sso_enabled"`
+	GlobalRole               *string    `json:"global_role"` // e.g., "admin", "maintainer", "observer"
+	Teams                    []UserTeam `json:"teams"`       // Teams the user belongs to and their role in each
+	APIOnly                  bool       `json:"api_only"`    // True if the user is an API-only user
+}
+
+// UserTeam represents a team a user belongs to and their role.
+type UserTeam struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"` // Role within the team, e.g., "admin", "maintainer", "observer"
+}
+
+// ListUsersResponse is the structure for the list users API response.
+// The API doc (https://fleetdm.com/docs/rest-api/rest-api#list-all-users) shows a response like:
+// { "users": [ { ...user_object... } ] }
+type ListUsersResponse struct {
+	Users []User `json:"users"`
+}
+
+// ListUsersOptions filters GET /api/v1/fleet/users.
+type ListUsersOptions struct {
+	ListOptions
+	Query  string // Matches name and email
+	TeamID *uint  // Fleet Premium only
+}
+
+// ListUsers lists the users until fn returns false.
+func (c *FleetDMClient) ListUsers(ctx context.Context, opts ListUsersOptions, fn func(user User) bool) error {
+	params := url.Values{}
+	setString(params, "query", opts.Query)
+	setUint(params, "team_id", opts.TeamID)
+
+	// FleetDM's /users endpoint does not seem to use a 'meta.has_next_results' field.
+	pages := paginator[User]{
+		Name:     "FleetDMClient.ListUsers",
+		Endpoint: "users",
+		Params:   params,
+		ItemsKey: "users",
+		Mode:     paginateByCount,
+		PageSize: 50, // A reasonable default, adjust as needed or if API has specific limits/max
+	}
+	return pages.walk(ctx, c, opts.ListOptions, fn)
+}
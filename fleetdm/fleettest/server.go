@@ -0,0 +1,802 @@
+// Package fleettest provides a fake Fleet API server for tests. It serves the
+// fixtures in testdata for every endpoint the plugin's tables call, pages them the
+// way Fleet does, rejects premium-only params on the free tier and can be told to
+// fail requests, so tables can be tested without a real Fleet instance.
+package fleettest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Credentials accepted by the fake server.
+const (
+	Token    = "fleettest-token"
+	Email    = "admin@example.com"
+	Password = "fleettest-password"
+)
+
+// License tiers the fake server can report from /config.
+const (
+	TierFree    = "free"
+	TierPremium = "premium"
+)
+
+// DefaultVersion is the Fleet version reported from /version unless configured.
+const DefaultVersion = "4.62.1"
+
+// DefaultCountsUpdatedAt is the counts_updated_at returned by the software and OS
+// version endpoints unless changed with SetCountsUpdatedAt.
+const DefaultCountsUpdatedAt = "2024-06-01T12:00:00Z"
+
+// apiPrefix is the path all Fleet API endpoints are served under.
+const apiPrefix = "/api/v1/fleet/"
+
+//go:embed testdata/*.json
+var fixtures embed.FS
+
+// Config selects the server the fake pretends to be.
+type Config struct {
+	// Tier is the license tier, TierPremium when empty
+	Tier string
+	// Version is the Fleet version, DefaultVersion when empty
+	Version string
+}
+
+// collection describes a list endpoint backed by a fixture.
+type collection struct {
+	// fixture is the file in testdata holding the endpoint's items, without ".json"
+	fixture string
+	// itemsKey is the JSON key holding the items, e.g. "hosts"
+	itemsKey string
+	// meta adds meta.has_next_results, for endpoints which return it in Fleet
+	meta bool
+	// counts adds count and counts_updated_at, as the software endpoints do
+	counts bool
+	// premium endpoints answer 402 on the free tier
+	premium bool
+	// premiumParams answer 402 on the free tier when sent
+	premiumParams []string
+	// filters maps query params to the item field which must equal them
+	filters map[string]string
+	// search lists the item fields matched by the `query` param
+	search []string
+	// match applies filters which are not plain field comparisons
+	match func(item map[string]any, query url.Values) bool
+	// populated maps params to the item field only returned when the param is "true"
+	populated map[string]string
+}
+
+var collections = map[string]collection{
+	"hosts": {
+		fixture:       "hosts",
+		itemsKey:      "hosts",
+		premiumParams: []string{"low_disk_space"},
+		filters:       map[string]string{"team_id": "team_id", "status": "status"},
+		search:        []string{"hostname", "hardware_serial", "uuid", "primary_ip"},
+		match: func(item map[string]any, query url.Values) bool {
+			return matchHostPolicy(item, query) && matchHostProfile(item, query)
+		},
+		populated: map[string]string{
+			"device_mapping":    "device_mapping",
+			"populate_policies": "policies",
+			"populate_users":    "users",
+			"populate_labels":   "labels",
+		},
+	},
+	"software/versions": {
+		fixture:       "software_versions",
+		itemsKey:      "software",
+		meta:          true,
+		counts:        true,
+		premiumParams: []string{"min_cvss_score", "max_cvss_score", "exploit"},
+		search:        []string{"name", "version"},
+		match: func(item map[string]any, query url.Values) bool {
+			return query.Get("vulnerable") != "true" || hasItems(item["vulnerabilities"])
+		},
+	},
+	"software/titles": {
+		fixture:       "software_titles",
+		itemsKey:      "software_titles",
+		meta:          true,
+		counts:        true,
+		premiumParams: []string{"packages_only", "min_cvss_score", "max_cvss_score", "exploit"},
+		search:        []string{"name", "display_name"},
+		match: func(item map[string]any, query url.Values) bool {
+			if query.Get("vulnerable") != "true" {
+				return true
+			}
+			versions, _ := item["versions"].([]any)
+			for _, version := range versions {
+				if v, ok := version.(map[string]any); ok && hasItems(v["vulnerabilities"]) {
+					return true
+				}
+			}
+			return false
+		},
+	},
+	"os_versions": {
+		fixture:  "os_versions",
+		itemsKey: "os_versions",
+		meta:     true,
+		counts:   true,
+		filters:  map[string]string{"platform": "platform", "os_name": "name_only", "os_version": "version"},
+	},
+	"software/fleet_maintained_apps": {
+		fixture:  "fleet_maintained_apps",
+		itemsKey: "fleet_maintained_apps",
+		meta:     true,
+		premium:  true,
+	},
+	"software/app_store_apps": {
+		fixture:  "app_store_apps",
+		itemsKey: "app_store_apps",
+		premium:  true,
+		filters:  map[string]string{"team_id": "team_id"},
+	},
+	"teams": {
+		fixture:  "teams",
+		itemsKey: "teams",
+		premium:  true,
+		search:   []string{"name"},
+	},
+	"activities": {
+		fixture:  "activities",
+		itemsKey: "activities",
+		meta:     true,
+		filters:  map[string]string{"activity_type": "type"},
+		search:   []string{"actor_full_name", "actor_email"},
+	},
+	"labels": {
+		fixture:       "labels",
+		itemsKey:      "labels",
+		premiumParams: []string{"team_id"},
+		filters:       map[string]string{"team_id": "team_id"},
+	},
+	"packs": {
+		fixture:  "packs",
+		itemsKey: "packs",
+	},
+	"global/policies": {
+		fixture:  "policies",
+		itemsKey: "policies",
+		match: func(item map[string]any, _ url.Values) bool {
+			return item["team_id"] == nil
+		},
+	},
+	"queries": {
+		fixture:       "queries",
+		itemsKey:      "queries",
+		premiumParams: []string{"team_id", "merge_inherited"},
+		filters:       map[string]string{"team_id": "team_id"},
+		search:        []string{"name", "query"},
+	},
+	"users": {
+		fixture:       "users",
+		itemsKey:      "users",
+		premiumParams: []string{"team_id"},
+		search:        []string{"name", "email"},
+	},
+	"carves": {
+		fixture:  "carves",
+		itemsKey: "carves",
+	},
+	"configuration_profiles": {
+		fixture:  "configuration_profiles",
+		itemsKey: "profiles",
+		meta:     true,
+		// Without team_id, Fleet lists the profiles of hosts without a team
+		match: func(item map[string]any, query url.Values) bool {
+			teamID := query.Get("team_id")
+			if teamID == "" {
+				teamID = "0"
+			}
+			return fmt.Sprint(item["team_id"]) == teamID
+		},
+	},
+}
+
+// matchHostPolicy applies the policy_id and policy_response host filters to the
+// responses in a host's policies.
+func matchHostPolicy(item map[string]any, query url.Values) bool {
+	if !query.Has("policy_id") {
+		return true
+	}
+	want := map[string]string{"passing": "pass", "failing": "fail"}[query.Get("policy_response")]
+	policies, _ := item["policies"].([]any)
+	for _, policy := range policies {
+		p, ok := policy.(map[string]any)
+		if !ok || fmt.Sprint(p["id"]) != query.Get("policy_id") {
+			continue
+		}
+		return want == "" || p["response"] == want
+	}
+	return false
+}
+
+// matchHostProfile applies the profile_uuid and profile_status host filters to
+// the profiles in a host's mdm object. Profiles without a status are pending.
+func matchHostProfile(item map[string]any, query url.Values) bool {
+	if !query.Has("profile_uuid") {
+		return true
+	}
+	mdm, _ := item["mdm"].(map[string]any)
+	profiles, _ := mdm["profiles"].([]any)
+	for _, profile := range profiles {
+		p, ok := profile.(map[string]any)
+		if !ok || p["profile_uuid"] != query.Get("profile_uuid") {
+			continue
+		}
+		status, _ := p["status"].(string)
+		if status == "" {
+			status = "pending"
+		}
+		return status == query.Get("profile_status")
+	}
+	return false
+}
+
+// teamPoliciesCollection serves /teams/:id/policies from the policies fixture.
+func teamPoliciesCollection(teamID string) collection {
+	return collection{
+		fixture:  "policies",
+		itemsKey: "policies",
+		premium:  true,
+		search:   []string{"name"},
+		match: func(item map[string]any, query url.Values) bool {
+			if item["team_id"] == nil {
+				return query.Get("merge_inherited") == "true"
+			}
+			return fmt.Sprint(item["team_id"]) == teamID
+		},
+	}
+}
+
+// hostSoftwareCollection serves /hosts/:id/software from the host_software fixture.
+func hostSoftwareCollection(hostID string) collection {
+	return collection{
+		fixture:  "host_software",
+		itemsKey: "software",
+		meta:     true,
+		search:   []string{"name"},
+		match: func(item map[string]any, query url.Values) bool {
+			if fmt.Sprint(item["host_id"]) != hostID {
+				return false
+			}
+			if query.Get("vulnerable") != "true" {
+				return true
+			}
+			versions, _ := item["installed_versions"].([]any)
+			for _, version := range versions {
+				if v, ok := version.(map[string]any); ok && hasItems(v["vulnerabilities"]) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// labelHosts holds the ids of the member hosts of each label in the labels
+// fixture, matching its host_count.
+var labelHosts = map[string][]string{
+	"6":  {"1", "2", "3", "4", "5"},
+	"7":  {"1"},
+	"12": {"2"},
+}
+
+// labelHostsCollection serves /labels/:id/hosts from the hosts fixture, with the
+// same filters as /hosts.
+func labelHostsCollection(labelID string) collection {
+	c := collections["hosts"]
+	c.match = func(item map[string]any, _ url.Values) bool {
+		return slices.Contains(labelHosts[labelID], fmt.Sprint(item["id"]))
+	}
+	return c
+}
+
+// profileStatuses holds the host counts served by
+// /configuration_profiles/:uuid/status for each profile in the
+// configuration_profiles fixture, matching the profiles of the hosts fixture.
+var profileStatuses = map[string]map[string]int{
+	"a6f1c2e0-1b7d-4c55-9e0f-1d2c3b4a5f01": {"verified": 1, "verifying": 0, "pending": 0, "failed": 0},
+	"a6f1c2e0-1b7d-4c55-9e0f-1d2c3b4a5f02": {"verified": 0, "verifying": 0, "pending": 0, "failed": 1},
+	"a6f1c2e0-1b7d-4c55-9e0f-1d2c3b4a5f03": {"verified": 0, "verifying": 0, "pending": 1, "failed": 0},
+	"a6f1c2e0-1b7d-4c55-9e0f-1d2c3b4a5f04": {"verified": 0, "verifying": 0, "pending": 0, "failed": 0},
+	"w2d9b7a4-6e3f-4a1c-8b2d-5f6e7a8b9c01": {"verified": 0, "verifying": 1, "pending": 0, "failed": 0},
+}
+
+// failure is an injected error response.
+type failure struct {
+	status int
+	// body replaces the Fleet error body when set
+	body string
+	// remaining is the number of requests still to fail, or -1 for all of them
+	remaining int
+}
+
+// request is a request received by the server.
+type request struct {
+	url    url.URL
+	header http.Header
+}
+
+// Server is a fake Fleet API server. Its URL is used as the connection's server_url.
+type Server struct {
+	*httptest.Server
+
+	tier    string
+	version string
+
+	mutex           sync.Mutex
+	requests        []request
+	failures        map[string]*failure
+	delays          map[string]time.Duration
+	countsUpdatedAt string
+}
+
+// NewServer starts a fake Fleet server which is closed when the test finishes.
+func NewServer(t testing.TB, config Config) *Server {
+	t.Helper()
+	s := &Server{
+		tier:            config.Tier,
+		version:         config.Version,
+		failures:        make(map[string]*failure),
+		delays:          make(map[string]time.Duration),
+		countsUpdatedAt: DefaultCountsUpdatedAt,
+	}
+	if s.tier == "" {
+		s.tier = TierPremium
+	}
+	if s.version == "" {
+		s.version = DefaultVersion
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// InjectError makes the next times requests to endpoint, e.g. "hosts" or
+// "hosts/1", fail with status. A times of zero or less fails every request.
+func (s *Server) InjectError(endpoint string, status int, times int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if times <= 0 {
+		times = -1
+	}
+	s.failures[strings.Trim(endpoint, "/")] = &failure{status: status, remaining: times}
+}
+
+// InjectResponse makes the next times requests to endpoint answer with status and
+// the raw body, e.g. a malformed or non-Fleet response. A times of zero or less
+// applies to every request.
+func (s *Server) InjectResponse(endpoint string, status int, body string, times int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if times <= 0 {
+		times = -1
+	}
+	s.failures[strings.Trim(endpoint, "/")] = &failure{status: status, body: body, remaining: times}
+}
+
+// SetDelay makes every request to endpoint wait for delay before it is answered,
+// like a slow listing on a large fleet.
+func (s *Server) SetDelay(endpoint string, delay time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.delays[strings.Trim(endpoint, "/")] = delay
+}
+
+// SetCountsUpdatedAt changes the counts_updated_at the software and OS version
+// endpoints report, as Fleet does after its periodic vulnerability run.
+func (s *Server) SetCountsUpdatedAt(timestamp string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.countsUpdatedAt = timestamp
+}
+
+// Requests returns the query params of every request made to endpoint, e.g.
+// "hosts", in the order they were received.
+func (s *Server) Requests(endpoint string) []url.Values {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var requests []url.Values
+	for _, r := range s.requests {
+		if strings.TrimPrefix(r.url.Path, apiPrefix) == strings.Trim(endpoint, "/") {
+			requests = append(requests, r.url.Query())
+		}
+	}
+	return requests
+}
+
+// RequestHeaders returns the headers of every request made to endpoint, in the
+// order they were received.
+func (s *Server) RequestHeaders(endpoint string) []http.Header {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var headers []http.Header
+	for _, r := range s.requests {
+		if strings.TrimPrefix(r.url.Path, apiPrefix) == strings.Trim(endpoint, "/") {
+			headers = append(headers, r.header)
+		}
+	}
+	return headers
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.requests = append(s.requests, request{url: *r.URL, header: r.Header.Clone()})
+	delay := s.delays[strings.TrimPrefix(r.URL.Path, apiPrefix)]
+	s.mutex.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	s.route(recorder, r)
+	writeResponse(w, r, recorder)
+}
+
+// writeResponse sends a routed response the way Fleet's HTTP stack does: successful
+// responses carry an ETag and answer a matching If-None-Match with 304 Not
+// Modified, and bodies are gzipped for clients which accept it.
+func writeResponse(w http.ResponseWriter, r *http.Request, recorder *httptest.ResponseRecorder) {
+	for name, values := range recorder.Header() {
+		w.Header()[name] = values
+	}
+	body := recorder.Body.Bytes()
+	if recorder.Code == http.StatusOK {
+		sum := sha256.Sum256(body)
+		etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.Header().Del("Content-Type")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		var compressed bytes.Buffer
+		zw := gzip.NewWriter(&compressed)
+		_, _ = zw.Write(body)
+		_ = zw.Close()
+		body = compressed.Bytes()
+		w.Header().Set("Content-Encoding", "gzip")
+	}
+	w.WriteHeader(recorder.Code)
+	_, _ = w.Write(body)
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, apiPrefix) {
+		writeError(w, http.StatusNotFound, "Resource Not Found", "unknown path "+r.URL.Path)
+		return
+	}
+	endpoint := strings.TrimPrefix(r.URL.Path, apiPrefix)
+
+	if f, ok := s.injectedFailure(endpoint); ok {
+		if f.body != "" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(f.status)
+			_, _ = w.Write([]byte(f.body))
+			return
+		}
+		writeError(w, f.status, http.StatusText(f.status), "injected failure")
+		return
+	}
+
+	if endpoint == "login" {
+		s.serveLogin(w, r)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+Token {
+		writeError(w, http.StatusUnauthorized, "Authentication required", "Authorization header required")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed", r.Method+" is not supported by fleettest")
+		return
+	}
+
+	switch {
+	case endpoint == "version":
+		writeJSON(w, http.StatusOK, map[string]any{
+			"version":    s.version,
+			"branch":     "main",
+			"revision":   "fleettest",
+			"go_version": "go1.22.4",
+			"build_date": "2024-06-01",
+			"build_user": "fleettest",
+		})
+	case endpoint == "config":
+		s.serveConfig(w)
+	case strings.HasPrefix(endpoint, "hosts/") && strings.HasSuffix(endpoint, "/software"):
+		hostID := strings.TrimSuffix(strings.TrimPrefix(endpoint, "hosts/"), "/software")
+		s.serveList(w, r, hostSoftwareCollection(hostID))
+	case strings.HasPrefix(endpoint, "hosts/"):
+		s.serveItem(w, "hosts", "host", strings.TrimPrefix(endpoint, "hosts/"), "Host was not found in the datastore")
+	case strings.HasPrefix(endpoint, "labels/") && strings.HasSuffix(endpoint, "/hosts"):
+		labelID := strings.TrimSuffix(strings.TrimPrefix(endpoint, "labels/"), "/hosts")
+		if _, ok := labelHosts[labelID]; !ok {
+			writeError(w, http.StatusNotFound, "Resource Not Found", "Label was not found in the datastore")
+			return
+		}
+		s.serveList(w, r, labelHostsCollection(labelID))
+	case strings.HasPrefix(endpoint, "labels/"):
+		s.serveItem(w, "labels", "label", strings.TrimPrefix(endpoint, "labels/"), "Label was not found in the datastore")
+	case strings.HasPrefix(endpoint, "teams/") && strings.HasSuffix(endpoint, "/policies"):
+		teamID := strings.TrimSuffix(strings.TrimPrefix(endpoint, "teams/"), "/policies")
+		s.serveList(w, r, teamPoliciesCollection(teamID))
+	case strings.HasPrefix(endpoint, "configuration_profiles/") && strings.HasSuffix(endpoint, "/status"):
+		status, ok := profileStatuses[strings.TrimSuffix(strings.TrimPrefix(endpoint, "configuration_profiles/"), "/status")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Resource Not Found", "Profile was not found in the datastore")
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	default:
+		c, ok := collections[endpoint]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Resource Not Found", "unknown endpoint "+endpoint)
+			return
+		}
+		s.serveList(w, r, c)
+	}
+}
+
+// injectedFailure returns the injected failure for endpoint, if any.
+func (s *Server) injectedFailure(endpoint string) (failure, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	f := s.failures[endpoint]
+	if f == nil || f.remaining == 0 {
+		return failure{}, false
+	}
+	if f.remaining > 0 {
+		f.remaining--
+	}
+	return *f, true
+}
+
+func (s *Server) serveLogin(w http.ResponseWriter, r *http.Request) {
+	var credentials struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&credentials) != nil {
+		writeError(w, http.StatusBadRequest, "Bad request", "expected a JSON body with email and password")
+		return
+	}
+	if credentials.Email != Email || credentials.Password != Password {
+		writeError(w, http.StatusUnauthorized, "Authentication failed", "invalid email or password")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"user":  map[string]any{"id": 1, "email": Email, "global_role": "admin"},
+		"token": Token,
+	})
+}
+
+func (s *Server) serveConfig(w http.ResponseWriter) {
+	license := map[string]any{"tier": s.tier}
+	if s.tier == TierPremium {
+		license["organization"] = "Fleet Test"
+		license["device_count"] = 100
+		license["expiration"] = "2030-01-01T00:00:00Z"
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"org_info": map[string]any{"org_name": "Fleet Test"},
+		"license":  license,
+	})
+}
+
+// serveItem serves GET /:fixture/:id, wrapping the fixture item with that id in key.
+func (s *Server) serveItem(w http.ResponseWriter, fixture, key, id, notFound string) {
+	items, err := loadFixture(fixture)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Internal Server Error", err.Error())
+		return
+	}
+	for _, item := range items {
+		if fmt.Sprint(item["id"]) == id {
+			writeJSON(w, http.StatusOK, map[string]any{key: item})
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "Resource Not Found", notFound)
+}
+
+// serveList filters, orders and pages a collection like Fleet's list endpoints:
+// `page` and `per_page` select a page, and `after` continues after the given
+// value of `order_key`. Without `per_page` every item is returned.
+func (s *Server) serveList(w http.ResponseWriter, r *http.Request, c collection) {
+	query := r.URL.Query()
+	if s.tier != TierPremium {
+		if c.premium {
+			writeError(w, http.StatusPaymentRequired, "Requires Fleet Premium license", "Requires Fleet Premium license")
+			return
+		}
+		for _, param := range c.premiumParams {
+			if query.Has(param) {
+				writeError(w, http.StatusPaymentRequired, "Requires Fleet Premium license", "Requires Fleet Premium license")
+				return
+			}
+		}
+	}
+
+	all, err := loadFixture(c.fixture)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Internal Server Error", err.Error())
+		return
+	}
+	items := make([]map[string]any, 0, len(all))
+	for _, item := range all {
+		if c.matches(item, query) {
+			items = append(items, item)
+		}
+	}
+	count := len(items)
+
+	if key := query.Get("order_key"); key != "" {
+		descending := query.Get("order_direction") == "desc"
+		sort.SliceStable(items, func(i, j int) bool {
+			if descending {
+				return compareFields(items[j][key], items[i][key]) < 0
+			}
+			return compareFields(items[i][key], items[j][key]) < 0
+		})
+		if after := query.Get("after"); after != "" {
+			kept := items[:0]
+			for _, item := range items {
+				order := compareFields(item[key], json.Number(after))
+				if (descending && order < 0) || (!descending && order > 0) {
+					kept = append(kept, item)
+				}
+			}
+			items = kept
+		}
+	}
+
+	start, end := 0, len(items)
+	if perPageParam := query.Get("per_page"); perPageParam != "" {
+		perPage, err := strconv.Atoi(perPageParam)
+		if err != nil || perPage < 1 {
+			writeError(w, http.StatusBadRequest, "Bad request", "invalid per_page "+perPageParam)
+			return
+		}
+		page, err := strconv.Atoi(query.Get("page"))
+		if err != nil && query.Has("page") {
+			writeError(w, http.StatusBadRequest, "Bad request", "invalid page "+query.Get("page"))
+			return
+		}
+		start = min(page*perPage, len(items))
+		end = min(start+perPage, len(items))
+	}
+
+	for _, item := range items[start:end] {
+		for param, field := range c.populated {
+			if query.Get(param) != "true" {
+				delete(item, field)
+			}
+		}
+	}
+
+	response := map[string]any{c.itemsKey: items[start:end]}
+	if c.meta {
+		response["meta"] = map[string]any{
+			"has_next_results":     end < len(items),
+			"has_previous_results": start > 0,
+		}
+	}
+	if c.counts {
+		s.mutex.Lock()
+		response["counts_updated_at"] = s.countsUpdatedAt
+		s.mutex.Unlock()
+		response["count"] = count
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// matches reports whether item passes the collection's filters for query.
+func (c collection) matches(item map[string]any, query url.Values) bool {
+	for param, field := range c.filters {
+		if !query.Has(param) {
+			continue
+		}
+		want := query.Get(param)
+		if want == "global" && item[field] == nil {
+			continue
+		}
+		if fmt.Sprint(item[field]) != want {
+			return false
+		}
+	}
+	if search := strings.ToLower(query.Get("query")); search != "" && len(c.search) > 0 {
+		found := false
+		for _, field := range c.search {
+			if value, ok := item[field].(string); ok && strings.Contains(strings.ToLower(value), search) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return c.match == nil || c.match(item, query)
+}
+
+// compareFields orders two fixture values, numerically when both are numbers.
+func compareFields(a, b any) int {
+	an, aok := a.(json.Number)
+	bn, bok := b.(json.Number)
+	if aok && bok {
+		af, _ := an.Float64()
+		bf, _ := bn.Float64()
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// hasItems reports whether a fixture value is a non-empty array.
+func hasItems(value any) bool {
+	items, ok := value.([]any)
+	return ok && len(items) > 0
+}
+
+// loadFixture decodes a fixture afresh, so responses never share state.
+func loadFixture(name string) ([]map[string]any, error) {
+	file, err := fixtures.Open("testdata/" + name + ".json")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	decoder.UseNumber()
+	var items []map[string]any
+	if err := decoder.Decode(&items); err != nil {
+		return nil, fmt.Errorf("error decoding fixture %s: %w", name, err)
+	}
+	return items, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes an error in Fleet's format.
+func writeError(w http.ResponseWriter, status int, message, reason string) {
+	writeJSON(w, status, map[string]any{
+		"message": message,
+		"errors":  []map[string]string{{"name": "base", "reason": reason}},
+		"uuid":    fmt.Sprintf("fleettest-%d", status),
+	})
+}
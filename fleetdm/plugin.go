@@ -3,35 +3,96 @@ package fleetdm
 import (
 	"context"
 
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleetapi"
+
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+	"github.com/turbot/steampipe-plugin-sdk/v5/rate_limiter"
+)
+
+// Endpoint families used to tag hydrate calls for rate limiting. The same names
+// key the per-connection limits configured in fleetdm.spc.
+const (
+	endpointFamilyHosts      = fleetapi.EndpointFamilyHosts
+	endpointFamilyHostDetail = fleetapi.EndpointFamilyHostDetail
+	endpointFamilySoftware   = fleetapi.EndpointFamilySoftware
+	endpointFamilyActivities = fleetapi.EndpointFamilyActivities
 )
 
+// endpointTag returns the hydrate tags which select the rate limiter for an endpoint family.
+func endpointTag(family string) map[string]string {
+	return map[string]string{"endpoint": family}
+}
+
 // Plugin returns the FleetDM plugin.
 func Plugin(ctx context.Context) *plugin.Plugin {
 	p := &plugin.Plugin{
 		Name: "steampipe-plugin-fleetdm",
 		ConnectionConfigSchema: &plugin.ConnectionConfigSchema{
 			NewInstance: ConfigInstance,
-			Schema:      ConfigSchema,
 		},
 		DefaultTransform: transform.FromGo().NullIfZero(),
+		// Default limits protect the Fleet server, which also serves live agents.
+		// One limiter instance exists per connection, Fleet server and endpoint
+		// family. The SDK resolves these definitions once for the plugin, so they
+		// cannot read fleetdm.spc. Users can override them with Steampipe limiter
+		// blocks, and tighten them per connection with the *_rate_limit options,
+		// which the connection's client enforces on every request.
+		RateLimiters: []*rate_limiter.Definition{
+			{
+				Name:       "fleetdm_hosts",
+				FillRate:   10,
+				BucketSize: 20,
+				Scope:      []string{"connection", matrixKeyServer, "endpoint"},
+				Where:      "endpoint = '" + endpointFamilyHosts + "'",
+			},
+			{
+				Name:       "fleetdm_software",
+				FillRate:   5,
+				BucketSize: 10,
+				Scope:      []string{"connection", matrixKeyServer, "endpoint"},
+				Where:      "endpoint = '" + endpointFamilySoftware + "'",
+			},
+			{
+				Name:       "fleetdm_activities",
+				FillRate:   5,
+				BucketSize: 10,
+				Scope:      []string{"connection", matrixKeyServer, "endpoint"},
+				Where:      "endpoint = '" + endpointFamilyActivities + "'",
+			},
+			{
+				// getHostDetails runs once per host, so it gets its own bucket and
+				// a cap on in-flight requests.
+				Name:           "fleetdm_host_detail",
+				FillRate:       10,
+				BucketSize:     10,
+				MaxConcurrency: 10,
+				Scope:          []string{"connection", matrixKeyServer, "endpoint"},
+				Where:          "endpoint = '" + endpointFamilyHostDetail + "'",
+			},
+		},
 		TableMap: map[string]*plugin.Table{
-			"fleetdm_activity":             tableFleetdmActivity(ctx),
-			"fleetdm_app_store_app":        tableFleetdmAppStoreApp(ctx),
-			"fleetdm_carve":                tableFleetdmCarve(ctx),
-			"fleetdm_fleet_maintained_app": tableFleetdmFleetMaintainedApp(ctx),
-			"fleetdm_host":                 tableFleetdmHost(ctx),
-			"fleetdm_host_detail":          tableFleetdmHostDetail(ctx),
-			"fleetdm_label":                tableFleetdmLabel(ctx),
-			"fleetdm_os_version":           tableFleetdmOSVersion(ctx),
-			"fleetdm_pack":                 tableFleetdmPack(ctx),
-			"fleetdm_policy":               tableFleetdmPolicy(ctx),
-			"fleetdm_query":                tableFleetdmQuery(ctx),
-			"fleetdm_software_title":       tableFleetdmSoftwareTitle(ctx),
-			"fleetdm_software_version":     tableFleetdmSoftwareVersion(ctx),
-			"fleetdm_team":                 tableFleetdmTeam(ctx),
-			"fleetdm_user":                 tableFleetdmUser(ctx),
+			"fleetdm_activity":              tableFleetdmActivity(ctx),
+			"fleetdm_app_store_app":         tableFleetdmAppStoreApp(ctx),
+			"fleetdm_configuration_profile": tableFleetdmConfigurationProfile(ctx),
+			"fleetdm_carve":                 tableFleetdmCarve(ctx),
+			"fleetdm_fleet_maintained_app":  tableFleetdmFleetMaintainedApp(ctx),
+			"fleetdm_host":                  tableFleetdmHost(ctx),
+			"fleetdm_host_detail":           tableFleetdmHostDetail(ctx),
+			"fleetdm_host_mdm_profile":      tableFleetdmHostMDMProfile(ctx),
+			"fleetdm_host_policy":           tableFleetdmHostPolicy(ctx),
+			"fleetdm_host_software":         tableFleetdmHostSoftware(ctx),
+			"fleetdm_label":                 tableFleetdmLabel(ctx),
+			"fleetdm_label_host":            tableFleetdmLabelHost(ctx),
+			"fleetdm_os_version":            tableFleetdmOSVersion(ctx),
+			"fleetdm_pack":                  tableFleetdmPack(ctx),
+			"fleetdm_policy":                tableFleetdmPolicy(ctx),
+			"fleetdm_query":                 tableFleetdmQuery(ctx),
+			"fleetdm_server_info":           tableFleetdmServerInfo(ctx),
+			"fleetdm_software_title":        tableFleetdmSoftwareTitle(ctx),
+			"fleetdm_software_version":      tableFleetdmSoftwareVersion(ctx),
+			"fleetdm_team":                  tableFleetdmTeam(ctx),
+			"fleetdm_user":                  tableFleetdmUser(ctx),
 		},
 	}
 	return p
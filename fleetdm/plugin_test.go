@@ -0,0 +1,254 @@
+package fleetdm_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm"
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/anywhere"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// testConnectionName is the name of the Steampipe connection used by every test.
+const testConnectionName = "fleetdm"
+
+// callID makes each query's call id unique across the test binary.
+var callID atomic.Int64
+
+func TestMain(m *testing.M) {
+	// The SDK keeps its caches under the install dir, so keep them out of ~/.steampipe
+	dir, err := os.MkdirTemp("", "steampipe-plugin-fleetdm-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Setenv("STEAMPIPE_INSTALL_DIR", dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testConnection runs queries against the plugin in-process, the same way
+// Steampipe does over gRPC.
+type testConnection struct {
+	t      *testing.T
+	server *grpc.PluginServer
+}
+
+// newTestConnection starts the plugin with a connection to fake. extraConfig is
+// appended to the connection's HCL config, e.g. "page_size = 2".
+func newTestConnection(t *testing.T, fake *fleettest.Server, extraConfig string) *testConnection {
+	t.Helper()
+	config := fmt.Sprintf("server_url = %q\napi_token = %q\n%s\n", fake.URL, fleettest.Token, extraConfig)
+	return newTestConnectionWithConfig(t, config)
+}
+
+// newTestConnectionWithConfig starts the plugin with the given HCL connection config.
+func newTestConnectionWithConfig(t *testing.T, config string) *testConnection {
+	t.Helper()
+	server := plugin.Server(&plugin.ServeOpts{PluginFunc: fleetdm.Plugin})
+	response, err := server.SetAllConnectionConfigs(&proto.SetAllConnectionConfigsRequest{
+		Configs: []*proto.ConnectionConfig{{
+			Connection: testConnectionName,
+			Plugin:     "fleetdm",
+			Config:     config,
+		}},
+		MaxCacheSizeMb: 16,
+	})
+	if err != nil {
+		t.Fatalf("SetAllConnectionConfigs: %v", err)
+	}
+	if len(response.FailedConnections) > 0 {
+		t.Fatalf("SetAllConnectionConfigs: %v", response.FailedConnections)
+	}
+	return &testConnection{t: t, server: server}
+}
+
+// testQuery is a query against one table. Quals are equality quals.
+type testQuery struct {
+	Table   string
+	Columns []string
+	Quals   map[string]any
+	Limit   int64
+}
+
+// row is an emitted row, keyed by column name. JSON columns are decoded.
+type row map[string]any
+
+// execute runs q and returns the emitted rows, or the error the query failed with.
+func (c *testConnection) execute(q testQuery) ([]row, error) {
+	c.t.Helper()
+	queryContext := &proto.QueryContext{
+		Columns: q.Columns,
+		Quals:   make(map[string]*proto.Quals),
+	}
+	for column, value := range q.Quals {
+		queryContext.Quals[column] = &proto.Quals{Quals: []*proto.Qual{{
+			FieldName: column,
+			Operator:  &proto.Qual_StringValue{StringValue: "="},
+			Value:     qualValue(c.t, value),
+		}}}
+	}
+	connectionData := &proto.ExecuteConnectionData{}
+	if q.Limit > 0 {
+		queryContext.Limit = &proto.NullableInt{Value: q.Limit}
+		connectionData.Limit = &proto.NullableInt{Value: q.Limit}
+	}
+
+	stream := anywhere.NewLocalPluginStream(context.Background())
+	c.server.CallExecuteAsync(&proto.ExecuteRequest{
+		Table:                 q.Table,
+		QueryContext:          queryContext,
+		CallId:                fmt.Sprintf("fleetdm-test-%d", callID.Add(1)),
+		Connection:            testConnectionName,
+		ExecuteConnectionData: map[string]*proto.ExecuteConnectionData{testConnectionName: connectionData},
+	}, stream)
+
+	var rows []row
+	for {
+		response, err := stream.Recv()
+		if err != nil {
+			return rows, err
+		}
+		if response == nil {
+			return rows, nil
+		}
+		if response.Row == nil {
+			continue
+		}
+		r := make(row, len(q.Columns))
+		for _, column := range q.Columns {
+			r[column] = columnValue(c.t, response.Row.Columns[column])
+		}
+		rows = append(rows, r)
+	}
+}
+
+// rows runs q and fails the test if the query fails.
+func (c *testConnection) rows(q testQuery) []row {
+	c.t.Helper()
+	rows, err := c.execute(q)
+	if err != nil {
+		c.t.Fatalf("query on %s failed: %v", q.Table, err)
+	}
+	return rows
+}
+
+func qualValue(t *testing.T, value any) *proto.QualValue {
+	t.Helper()
+	switch v := value.(type) {
+	case string:
+		return &proto.QualValue{Value: &proto.QualValue_StringValue{StringValue: v}}
+	case int:
+		return &proto.QualValue{Value: &proto.QualValue_Int64Value{Int64Value: int64(v)}}
+	case int64:
+		return &proto.QualValue{Value: &proto.QualValue_Int64Value{Int64Value: v}}
+	case bool:
+		return &proto.QualValue{Value: &proto.QualValue_BoolValue{BoolValue: v}}
+	case float64:
+		return &proto.QualValue{Value: &proto.QualValue_DoubleValue{DoubleValue: v}}
+	}
+	t.Fatalf("unsupported qual value %T", value)
+	return nil
+}
+
+// columnValue converts a column to a Go value: nil, string, int64, float64, bool,
+// a decoded JSON value, or a timestamp formatted as RFC 3339.
+func columnValue(t *testing.T, column *proto.Column) any {
+	t.Helper()
+	if column == nil {
+		return nil
+	}
+	switch v := column.Value.(type) {
+	case *proto.Column_NullValue:
+		return nil
+	case *proto.Column_StringValue:
+		return v.StringValue
+	case *proto.Column_IntValue:
+		return v.IntValue
+	case *proto.Column_DoubleValue:
+		return v.DoubleValue
+	case *proto.Column_BoolValue:
+		return v.BoolValue
+	case *proto.Column_TimestampValue:
+		return v.TimestampValue.AsTime().UTC().Format("2006-01-02T15:04:05Z07:00")
+	case *proto.Column_IpAddrValue:
+		return v.IpAddrValue
+	case *proto.Column_JsonValue:
+		var decoded any
+		if err := json.Unmarshal(v.JsonValue, &decoded); err != nil {
+			t.Fatalf("column has invalid JSON %q: %v", v.JsonValue, err)
+		}
+		return decoded
+	}
+	t.Fatalf("unsupported column value %T", column.Value)
+	return nil
+}
+
+// columnValues returns the value of column for each row.
+func columnValues(rows []row, column string) []any {
+	values := make([]any, len(rows))
+	for i, r := range rows {
+		values[i] = r[column]
+	}
+	return values
+}
+
+// assertColumn fails the test unless the rows have exactly the given values for
+// column, in any order. Hydrates run concurrently, so row order is not stable.
+func assertColumn(t *testing.T, rows []row, column string, want ...any) {
+	t.Helper()
+	got := columnValues(rows, column)
+	if !sameValues(got, want) {
+		t.Errorf("%s = %v, want %v", column, got, want)
+	}
+}
+
+// sameValues compares two lists of values ignoring order.
+func sameValues(a, b []any) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	formatted := func(values []any) []string {
+		s := make([]string, len(values))
+		for i, v := range values {
+			if n, ok := v.(int); ok {
+				v = int64(n) // Integer columns are emitted as int64
+			}
+			s[i] = fmt.Sprintf("%T:%v", v, v)
+		}
+		sort.Strings(s)
+		return s
+	}
+	return slices.Equal(formatted(a), formatted(b))
+}
+
+// assertRequests fails the test unless fake received want requests to endpoint.
+func assertRequests(t *testing.T, fake *fleettest.Server, endpoint string, want int) {
+	t.Helper()
+	if got := len(fake.Requests(endpoint)); got != want {
+		t.Errorf("requests to %s = %d, want %d: %v", endpoint, got, want, fake.Requests(endpoint))
+	}
+}
+
+// assertErrorContains fails the test unless err mentions want.
+func assertErrorContains(t *testing.T, err error, want string) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected an error containing %q, got none", want)
+	}
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q does not contain %q", err, want)
+	}
+}
@@ -0,0 +1,95 @@
+package fleetdm_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+// assertErrorOmits fails the test if err mentions any of the secrets.
+func assertErrorOmits(t *testing.T, err error, secrets ...string) {
+	t.Helper()
+	if err == nil {
+		t.Fatal("expected an error, got none")
+	}
+	for _, secret := range secrets {
+		if strings.Contains(err.Error(), secret) {
+			t.Errorf("error %q leaks %q", err, secret)
+		}
+	}
+}
+
+func TestRedactErrorBody(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	// A proxy or an older Fleet may answer with something other than a Fleet error
+	fake.InjectResponse("teams", 500, `{"team":{"id":1,"secrets":[{"secret":"enroll-s3cret"}]},"token":"tok-123"}`, 0)
+	conn := newTestConnection(t, fake, "")
+
+	_, err := conn.execute(testQuery{Table: "fleetdm_team", Columns: []string{"id"}})
+
+	assertErrorContains(t, err, "500 Internal Server Error")
+	assertErrorContains(t, err, `"secrets":"<redacted>"`)
+	assertErrorContains(t, err, `"token":"<redacted>"`)
+	assertErrorOmits(t, err, "enroll-s3cret", "tok-123")
+}
+
+func TestRedactDecodeErrorBody(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	fake.InjectResponse("hosts/1", 200, `{"host":{"id":"one","orbit_node_key":"node-key-123","disk_encryption_key":{"key":"recovery-456"}}}`, 0)
+	conn := newTestConnection(t, fake, "")
+
+	_, err := conn.execute(testQuery{Table: "fleetdm_host_detail", Columns: []string{"id", "hostname"}, Quals: map[string]any{"id": 1}})
+
+	assertErrorContains(t, err, "error decoding JSON response")
+	assertErrorContains(t, err, `"orbit_node_key":"<redacted>"`)
+	assertErrorOmits(t, err, "node-key-123", "recovery-456")
+}
+
+func TestRedactTruncatedErrorBody(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	// Not valid JSON, so the secret is masked by pattern
+	fake.InjectResponse("users", 500, `{"users":[{"id":1,"api_token":"tok-789","name":"Alice"`, 0)
+	conn := newTestConnection(t, fake, "")
+
+	_, err := conn.execute(testQuery{Table: "fleetdm_user", Columns: []string{"id"}})
+
+	assertErrorContains(t, err, `"api_token":"<redacted>"`)
+	assertErrorContains(t, err, `"name":"Alice"`)
+	assertErrorOmits(t, err, "tok-789")
+}
+
+func TestRedactURLPassword(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	fake.InjectError("labels", 500, 0)
+	serverURL := strings.Replace(fake.URL, "http://", "http://fleet:hunter2@", 1)
+	conn := newTestConnectionWithConfig(t, `server_url = "`+serverURL+`"
+api_token = "`+fleettest.Token+`"
+`)
+
+	_, err := conn.execute(testQuery{Table: "fleetdm_label", Columns: []string{"id"}})
+
+	assertErrorContains(t, err, "500 Internal Server Error")
+	assertErrorOmits(t, err, "hunter2")
+}
+
+func TestDebugLogBodiesRejectsEmptyEndpoint(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, `debug_log_bodies = ["hosts", ""]`)
+
+	_, err := conn.execute(testQuery{Table: "fleetdm_host", Columns: []string{"id"}})
+
+	assertErrorContains(t, err, "debug_log_bodies must not contain an empty endpoint")
+}
+
+func TestDebugLogBodiesKeepsErrorsRedacted(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	fake.InjectResponse("teams", 500, `{"secrets":[{"secret":"enroll-s3cret"}]}`, 0)
+	conn := newTestConnection(t, fake, `debug_log_bodies = ["*"]`)
+
+	// Full bodies only go to the debug log, never into errors returned to the user
+	_, err := conn.execute(testQuery{Table: "fleetdm_team", Columns: []string{"id"}})
+
+	assertErrorContains(t, err, `"secrets":"<redacted>"`)
+	assertErrorOmits(t, err, "enroll-s3cret")
+}
@@ -0,0 +1,138 @@
+package fleetdm_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+// Each test opens a new connection per query, the way a restarted Steampipe
+// would, so only the on-disk cache can be shared between them.
+
+func TestResponseCacheHosts(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	config := fmt.Sprintf("cache_dir = %q\nhosts_cache_ttl = 300", t.TempDir())
+	query := testQuery{Table: "fleetdm_host", Columns: []string{"id", "hostname"}}
+
+	first := newTestConnection(t, fake, config).rows(query)
+	second := newTestConnection(t, fake, config).rows(query)
+
+	assertColumn(t, first, "id", 1, 2, 3, 4, 5)
+	assertColumn(t, second, "id", 1, 2, 3, 4, 5)
+	assertColumn(t, second, "hostname", columnValues(first, "hostname")...)
+	assertRequests(t, fake, "hosts", 1)
+}
+
+func TestResponseCacheScopedToCredentials(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	cacheDir := t.TempDir()
+	cacheConfig := fmt.Sprintf("cache_dir = %q\nhosts_cache_ttl = 300", cacheDir)
+	query := testQuery{Table: "fleetdm_host", Columns: []string{"id"}}
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte(fleettest.Token), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fileConfig := fmt.Sprintf("server_url = %q\napi_token_file = %q\n%s\n", fake.URL, tokenFile, cacheConfig)
+
+	// Another credential, which might be scoped to other teams, must not read the cached pages
+	newTestConnection(t, fake, cacheConfig).rows(query)
+	newTestConnectionWithConfig(t, fileConfig).rows(query)
+	assertRequests(t, fake, "hosts", 2)
+
+	newTestConnectionWithConfig(t, fileConfig).rows(query)
+	assertRequests(t, fake, "hosts", 2)
+}
+
+func TestResponseCacheBypass(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	config := fmt.Sprintf("cache_dir = %q\nhosts_cache_ttl = 300", t.TempDir())
+
+	newTestConnection(t, fake, config).rows(testQuery{Table: "fleetdm_host", Columns: []string{"id"}})
+	rows := newTestConnection(t, fake, config).rows(testQuery{
+		Table:   "fleetdm_host",
+		Columns: []string{"id", "cache_bypass"},
+		Quals:   map[string]any{"cache_bypass": true},
+	})
+
+	assertColumn(t, rows, "id", 1, 2, 3, 4, 5)
+	assertColumn(t, rows, "cache_bypass", true, true, true, true, true)
+	assertRequests(t, fake, "hosts", 2)
+}
+
+func TestResponseCacheFamilyWithoutTTL(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	config := fmt.Sprintf("cache_dir = %q\nsoftware_cache_ttl = 300", t.TempDir())
+	query := testQuery{Table: "fleetdm_host", Columns: []string{"id"}}
+
+	newTestConnection(t, fake, config).rows(query)
+	newTestConnection(t, fake, config).rows(query)
+
+	assertRequests(t, fake, "hosts", 2)
+}
+
+func TestResponseCacheCountsUpdatedAt(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	config := fmt.Sprintf("cache_dir = %q\nsoftware_cache_ttl = 3600", t.TempDir())
+	query := testQuery{Table: "fleetdm_software_version", Columns: []string{"id"}}
+
+	newTestConnection(t, fake, config).rows(query)
+	assertRequests(t, fake, "software/versions", 1)
+
+	// An unchanged counts_updated_at only costs a one-item probe
+	rows := newTestConnection(t, fake, config).rows(query)
+	assertColumn(t, rows, "id", 1, 2, 3)
+	assertRequests(t, fake, "software/versions", 2)
+	if probe := fake.Requests("software/versions")[1]; probe.Get("per_page") != "1" {
+		t.Errorf("probe params = %v, want per_page=1", probe)
+	}
+
+	// Once Fleet recomputes the counts, the cached pages are fetched again
+	fake.SetCountsUpdatedAt("2024-06-01T13:00:00Z")
+	rows = newTestConnection(t, fake, config).rows(query)
+	assertColumn(t, rows, "id", 1, 2, 3)
+	assertRequests(t, fake, "software/versions", 4)
+}
+
+func TestResponseCacheHostDetail(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	config := fmt.Sprintf("cache_dir = %q\nhost_detail_cache_ttl = 900", t.TempDir())
+	query := testQuery{
+		Table:   "fleetdm_host_detail",
+		Columns: []string{"id", "hostname"},
+		Quals:   map[string]any{"id": 1},
+	}
+
+	first := newTestConnection(t, fake, config).rows(query)
+	second := newTestConnection(t, fake, config).rows(query)
+
+	assertColumn(t, second, "hostname", columnValues(first, "hostname")...)
+	assertRequests(t, fake, "hosts/1", 1)
+}
+
+func TestResponseCacheTTLWithoutDir(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "hosts_cache_ttl = 300")
+
+	_, err := conn.execute(testQuery{Table: "fleetdm_host", Columns: []string{"id"}})
+
+	assertErrorContains(t, err, "require cache_dir")
+}
+
+func TestResponseCacheLabelHosts(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	config := fmt.Sprintf("cache_dir = %q\nhosts_cache_ttl = 300", t.TempDir())
+	query := testQuery{Table: "fleetdm_label_host", Columns: []string{"host_id"}, Quals: map[string]any{"label_id": 6}}
+
+	newTestConnection(t, fake, config).rows(query)
+	rows := newTestConnection(t, fake, config).rows(query)
+	assertColumn(t, rows, "host_id", 1, 2, 3, 4, 5)
+	assertRequests(t, fake, "labels/6/hosts", 1)
+
+	query.Quals["cache_bypass"] = true
+	newTestConnection(t, fake, config).rows(query)
+	assertRequests(t, fake, "labels/6/hosts", 2)
+}
@@ -0,0 +1,117 @@
+package fleetdm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// matrixKeyServer names the matrix item, column and qual holding the name of the
+// Fleet server a row came from.
+const matrixKeyServer = "server"
+
+// fleetServer is one entry of the servers option. The list is decoded from an HCL
+// attribute, so the fields carry cty tags as well.
+type fleetServer struct {
+	Name  string `hcl:"name" cty:"name"`
+	URL   string `hcl:"url" cty:"url"`
+	Token string `hcl:"token" cty:"token"`
+}
+
+// connectionServers returns the Fleet servers a connection queries. Without the
+// servers option, the connection has one server named after the connection and
+// configured by server_url and the API token options.
+func connectionServers(connection *plugin.Connection) ([]fleetServer, error) {
+	config := GetConfig(connection)
+	if len(config.Servers) == 0 {
+		name := ""
+		if connection != nil {
+			name = connection.Name
+		}
+		return []fleetServer{{Name: name}}, nil
+	}
+
+	if config.ServerURL != nil && *config.ServerURL != "" {
+		return nil, errors.New("server_url and servers cannot both be set")
+	}
+	seen := make(map[string]bool, len(config.Servers))
+	for _, server := range config.Servers {
+		switch {
+		case strings.TrimSpace(server.Name) == "":
+			return nil, errors.New("every entry of servers must have a name")
+		case seen[server.Name]:
+			return nil, fmt.Errorf("servers contains the name '%s' more than once", server.Name)
+		case server.URL == "":
+			return nil, fmt.Errorf("server '%s' must have a url", server.Name)
+		case server.Token == "":
+			return nil, fmt.Errorf("server '%s' must have a token", server.Name)
+		}
+		seen[server.Name] = true
+	}
+	return config.Servers, nil
+}
+
+// serverConfig returns the connection config for one entry of the servers option:
+// its url and token replace server_url and the API token options, and cassettes
+// are kept in a subdirectory per server, as they are named by path only. Every
+// other option applies to all servers.
+func serverConfig(config fleetdmConfig, server fleetServer) fleetdmConfig {
+	config.Servers = nil
+	config.ServerURL = &server.URL
+	config.APIToken = &server.Token
+	config.APITokenFile = nil
+	config.APITokenCommand = nil
+	config.Email = nil
+	config.Password = nil
+	if config.RecordDir != nil && *config.RecordDir != "" {
+		recordDir := filepath.Join(*config.RecordDir, server.Name)
+		config.RecordDir = &recordDir
+	}
+	if config.ReplayDir != nil && *config.ReplayDir != "" {
+		replayDir := filepath.Join(*config.ReplayDir, server.Name)
+		config.ReplayDir = &replayDir
+	}
+	return config
+}
+
+// serverMatrix fans every table out to the connection's Fleet servers. The SDK
+// runs the list (and get) hydrates concurrently, once per server, and skips the
+// servers excluded by a `server` qual.
+func serverMatrix(ctx context.Context, d *plugin.QueryData) []map[string]interface{} {
+	servers, err := connectionServers(d.Connection)
+	if err != nil {
+		// getClient reports the error when the hydrate runs
+		plugin.Logger(ctx).Error("serverMatrix", "config_error", err)
+		return []map[string]interface{}{{matrixKeyServer: d.Connection.Name}}
+	}
+	matrix := make([]map[string]interface{}, len(servers))
+	for i, server := range servers {
+		matrix[i] = map[string]interface{}{matrixKeyServer: server.Name}
+	}
+	return matrix
+}
+
+// serverFromContext returns the name of the Fleet server a hydrate runs for.
+func serverFromContext(ctx context.Context) string {
+	server, _ := plugin.GetMatrixItem(ctx)[matrixKeyServer].(string)
+	return server
+}
+
+// withServerColumn adds the server column to a table's columns. Tables also take
+// an optional server key column, so `where server = 'prod'` only queries prod.
+// The SDK adds each matrix item to the quals of the hydrates run for it, which,
+// unlike the matrix item itself, also reach the rows of get calls.
+func withServerColumn(columns []*plugin.Column) []*plugin.Column {
+	return append(columns, &plugin.Column{
+		Name:        matrixKeyServer,
+		Type:        proto.ColumnType_STRING,
+		Transform:   transform.FromQual(matrixKeyServer),
+		Description: "Name of the Fleet server the row came from, from the servers option, or the connection name for connections configured with server_url.",
+	})
+}
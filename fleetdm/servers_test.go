@@ -0,0 +1,147 @@
+package fleetdm_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+// newTestServersConnection starts the plugin with a connection to each of fakes,
+// keyed by server name, through the servers option.
+func newTestServersConnection(t *testing.T, fakes map[string]*fleettest.Server, extraConfig string) *testConnection {
+	t.Helper()
+	config := "servers = [\n"
+	for name, fake := range fakes {
+		config += fmt.Sprintf("  { name = %q, url = %q, token = %q },\n", name, fake.URL, fleettest.Token)
+	}
+	config += "]\n" + extraConfig + "\n"
+	return newTestConnectionWithConfig(t, config)
+}
+
+func TestServersFanOut(t *testing.T) {
+	prod := fleettest.NewServer(t, fleettest.Config{})
+	lab := fleettest.NewServer(t, fleettest.Config{Tier: fleettest.TierFree})
+	conn := newTestServersConnection(t, map[string]*fleettest.Server{"prod": prod, "lab": lab}, "")
+
+	rows := conn.rows(testQuery{Table: "fleetdm_label", Columns: []string{"name", "server"}})
+
+	assertColumn(t, rows, "server", "prod", "prod", "prod", "lab", "lab", "lab")
+	assertRequests(t, prod, "labels", 1)
+	assertRequests(t, lab, "labels", 1)
+
+	// Each server's license is detected separately
+	rows = conn.rows(testQuery{Table: "fleetdm_server_info", Columns: []string{"server", "server_url", "license_tier"}})
+	for _, r := range rows {
+		want := map[string]row{
+			"prod": {"server": "prod", "server_url": prod.URL, "license_tier": "premium"},
+			"lab":  {"server": "lab", "server_url": lab.URL, "license_tier": "free"},
+		}[r["server"].(string)]
+		for column, value := range want {
+			if r[column] != value {
+				t.Errorf("%s = %v, want %v", column, r[column], value)
+			}
+		}
+	}
+	assertColumn(t, rows, "server", "prod", "lab")
+}
+
+func TestServersQual(t *testing.T) {
+	prod := fleettest.NewServer(t, fleettest.Config{})
+	lab := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestServersConnection(t, map[string]*fleettest.Server{"prod": prod, "lab": lab}, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host",
+		Columns: []string{"id", "server"},
+		Quals:   map[string]any{"server": "lab"},
+	})
+
+	assertColumn(t, rows, "server", "lab", "lab", "lab", "lab", "lab")
+	assertRequests(t, prod, "hosts", 0)
+	assertRequests(t, lab, "hosts", 1)
+}
+
+func TestServersSingleServerColumn(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{Table: "fleetdm_team", Columns: []string{"id", "server"}})
+
+	// Connections configured with server_url are named after the connection
+	for _, r := range rows {
+		if r["server"] != testConnectionName {
+			t.Errorf("server = %v, want %q", r["server"], testConnectionName)
+		}
+	}
+}
+
+func TestServersRejectsDuplicateNames(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnectionWithConfig(t, fmt.Sprintf(`servers = [
+  { name = "prod", url = %[1]q, token = %[2]q },
+  { name = "prod", url = %[1]q, token = %[2]q },
+]
+`, fake.URL, fleettest.Token))
+
+	_, err := conn.execute(testQuery{Table: "fleetdm_label", Columns: []string{"id"}})
+
+	assertErrorContains(t, err, "servers contains the name 'prod' more than once")
+}
+
+func TestServersRejectsServerURL(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestServersConnection(t, map[string]*fleettest.Server{"prod": fake}, fmt.Sprintf("server_url = %q", fake.URL))
+
+	_, err := conn.execute(testQuery{Table: "fleetdm_label", Columns: []string{"id"}})
+
+	assertErrorContains(t, err, "server_url and servers cannot both be set")
+}
+
+func TestServersHostDetail(t *testing.T) {
+	prod := fleettest.NewServer(t, fleettest.Config{})
+	lab := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestServersConnection(t, map[string]*fleettest.Server{"prod": prod, "lab": lab}, "")
+
+	// Host ids are only unique within a server, so a get call names the server too
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_detail",
+		Columns: []string{"id", "hostname", "server"},
+		Quals:   map[string]any{"id": 3, "server": "lab"},
+	})
+
+	assertColumn(t, rows, "server", "lab")
+	assertRequests(t, prod, "hosts/3", 0)
+	assertRequests(t, lab, "hosts/3", 1)
+
+	// Listed hosts are hydrated from the server they were listed from
+	rows = conn.rows(testQuery{Table: "fleetdm_host_detail", Columns: []string{"id", "software", "server"}})
+
+	assertColumn(t, rows, "server", "prod", "prod", "prod", "prod", "prod", "lab", "lab", "lab", "lab", "lab")
+	assertRequests(t, prod, "hosts/1", 1)
+	assertRequests(t, lab, "hosts/1", 1)
+}
+
+func TestServersDetectConcurrently(t *testing.T) {
+	prod := fleettest.NewServer(t, fleettest.Config{})
+	lab := fleettest.NewServer(t, fleettest.Config{})
+	lab.SetDelay("version", 2*time.Second)
+	conn := newTestServersConnection(t, map[string]*fleettest.Server{"prod": prod, "lab": lab}, "")
+	query := func(server string) testQuery {
+		return testQuery{Table: "fleetdm_server_info", Columns: []string{"server", "version"}, Quals: map[string]any{"server": server}}
+	}
+
+	done := make(chan []row)
+	go func() { done <- conn.rows(query("lab")) }()
+	time.Sleep(200 * time.Millisecond)
+
+	// A slow server must not hold up detection for the others
+	start := time.Now()
+	rows := conn.rows(query("prod"))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("prod detection took %s while lab was slow", elapsed)
+	}
+	assertColumn(t, rows, "server", "prod")
+	assertColumn(t, <-done, "server", "lab")
+}
@@ -2,58 +2,33 @@ package fleetdm
 
 import (
 	"context"
-	"encoding/json" // For json.RawMessage
-	"net/url"
-	"strconv"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleetapi"
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
-// Activity represents an audit log activity in FleetDM.
-// Refer to: https://fleetdm.com/docs/rest-api/rest-api#activity-object
-type Activity struct {
-	ID              uint            `json:"id"`
-	CreatedAt       FleetTime       `json:"created_at"`
-	ActorFullName   string          `json:"actor_full_name"`
-	ActorID         *uint           `json:"actor_id"` // Can be null for system activities
-	ActorGravatar   string          `json:"actor_gravatar"`
-	Type            string          `json:"type"`                        // e.g., "created_user", "deleted_pack", "live_query"
-	Details         json.RawMessage `json:"details"`                     // JSON object, structure varies by type
-	ActorEmail      *string         `json:"actor_email,omitempty"`       // Not in main doc, but often present
-	ActorType       string          `json:"actor_type,omitempty"`        // e.g. "user", "system" - not in main doc but useful
-	HostID          *uint           `json:"host_id,omitempty"`           // If activity relates to a specific host
-	HostDisplayName *string         `json:"host_display_name,omitempty"` // If activity relates to a specific host
-}
-
-// ListActivitiesResponse for `GET /api/v1/fleet/activities`
-// The API returns {"activities": [...]}
-type ListActivitiesResponse struct {
-	Activities []Activity `json:"activities"`
-	Meta       struct {   // FleetDM API for activities includes a meta object for pagination
-		HasNextResults     bool   `json:"has_next_results"`
-		HasPreviousResults bool   `json:"has_previous_results"`
-		NextCursor         string `json:"next_cursor"` // The API docs mention 'after' parameter with a timestamp, but also page/per_page. Let's assume page/per_page for now as per examples.
-	} `json:"meta"`
-	Count int `json:"count"` // Total count of activities matching the query
-}
-
 func tableFleetdmActivity(ctx context.Context) *plugin.Table {
 	return &plugin.Table{
-		Name:        "fleetdm_activity",
-		Description: "Audit log activities in FleetDM.",
+		Name:              "fleetdm_activity",
+		Description:       "Audit log activities in FleetDM.",
+		GetMatrixItemFunc: serverMatrix,
 		List: &plugin.ListConfig{
 			Hydrate: listActivities,
+			Tags:    endpointTag(endpointFamilyActivities),
 			KeyColumns: []*plugin.KeyColumn{
 				{Name: "type", Require: plugin.Optional},             // Maps to API 'activity_type' param
 				{Name: "query", Require: plugin.Optional},            // Search by actor_full_name or actor_email
 				{Name: "start_created_at", Require: plugin.Optional}, // Filter activities after this date
 				{Name: "end_created_at", Require: plugin.Optional},   // Filter activities before this date
+				{Name: "cache_bypass", Require: plugin.Optional},     // Skip the response cache
+				{Name: "server", Require: plugin.Optional},           // Query only this Fleet server
 			},
 		},
 		// No GetConfig for activities as individual activity GET is not standard.
-		Columns: []*plugin.Column{
+		Columns: withServerColumn([]*plugin.Column{
 			{Name: "id", Type: proto.ColumnType_INT, Description: "Unique ID of the activity."},
 			{Name: "created_at", Type: proto.ColumnType_TIMESTAMP, Transform: transform.FromField("CreatedAt").Transform(flexibleTimeTransform), Description: "Timestamp when the activity occurred."},
 			{Name: "actor_full_name", Type: proto.ColumnType_STRING, Description: "Full name of the actor who performed the activity."},
@@ -69,76 +44,25 @@ func tableFleetdmActivity(ctx context.Context) *plugin.Table {
 			{Name: "query", Type: proto.ColumnType_STRING, Transform: transform.FromQual("query"), Description: "Search query keywords. Searchable fields include actor_full_name and actor_email. Set in WHERE clause."},
 			{Name: "start_created_at", Type: proto.ColumnType_STRING, Transform: transform.FromQual("start_created_at"), Description: "Filter activities that happened after this date (e.g., '2024-01-01T00:00:00Z'). Set in WHERE clause."},
 			{Name: "end_created_at", Type: proto.ColumnType_STRING, Transform: transform.FromQual("end_created_at"), Description: "Filter activities that happened before this date (e.g., '2024-12-31T23:59:59Z'). Set in WHERE clause."},
-		},
+			{Name: "cache_bypass", Type: proto.ColumnType_BOOL, Transform: transform.FromQual("cache_bypass"), Description: "Set to true to skip the response cache configured with cache_dir and fetch fresh data. Set in WHERE clause."},
+		}),
 	}
 }
 
 func listActivities(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
-	client, err := NewFleetDMClient(ctx, d.Connection)
+	ctx = cacheBypassContext(ctx, d)
+	client, err := getClient(ctx, d)
 	if err != nil {
 		plugin.Logger(ctx).Error("fleetdm_activity.listActivities", "connection_error", err)
 		return nil, err
 	}
 
-	// Pagination for activities: The /api/v1/fleet/activities endpoint supports `page` and `per_page`
-	// It also supports `after` (timestamp string like "2022-11-22T17:39:00Z") for cursor-based pagination.
-	// We'll use page/per_page for consistency with other tables.
-	page := 0
-	perPage := 50 // API default is 20, max 100
-
-	// limit := d.QueryContext.Limit
-	// if limit != nil && *limit < int64(perPage) {
-	// 	// perPage = int(*limit)
-	// }
-
-	for {
-		params := url.Values{}
-		params.Add("page", strconv.Itoa(page))
-		params.Add("per_page", strconv.Itoa(perPage))
-		params.Add("order_key", "id")
-		params.Add("order_direction", "asc") // Most recent (highest ID) last
-
-		if d.EqualsQuals["type"] != nil {
-			params.Add("activity_type", d.EqualsQuals["type"].GetStringValue())
-		}
-		if d.EqualsQuals["query"] != nil {
-			params.Add("query", d.EqualsQuals["query"].GetStringValue())
-		}
-		if d.EqualsQuals["start_created_at"] != nil {
-			params.Add("start_created_at", d.EqualsQuals["start_created_at"].GetStringValue())
-		}
-		if d.EqualsQuals["end_created_at"] != nil {
-			params.Add("end_created_at", d.EqualsQuals["end_created_at"].GetStringValue())
-		}
-
-		var response ListActivitiesResponse
-		_, err := client.Get(ctx, "activities", params, &response)
-		if err != nil {
-			plugin.Logger(ctx).Error("fleetdm_activity.listActivities", "api_error", err, "page", page, "params", params.Encode())
-			return nil, err
-		}
-
-		for _, activity := range response.Activities {
-			d.StreamListItem(ctx, activity)
-			if d.RowsRemaining(ctx) == 0 {
-				plugin.Logger(ctx).Debug("fleetdm_activity.listActivities", "limit_reached", true)
-				return nil, nil
-			}
-		}
-
-		// Pagination check
-		if !response.Meta.HasNextResults && response.Meta.NextCursor == "" {
-			plugin.Logger(ctx).Debug("fleetdm_activity.listActivities", "end_of_results_by_meta", true, "activities_on_page", len(response.Activities), "has_next_meta", response.Meta.HasNextResults)
-			break
-		}
-		if len(response.Activities) < perPage { // Fallback if meta isn't conclusive with page/per_page
-			plugin.Logger(ctx).Debug("fleetdm_activity.listActivities", "end_of_results_by_count", true, "activities_on_page", len(response.Activities))
-			break
-		}
-
-		page++
-		plugin.Logger(ctx).Debug("fleetdm_activity.listActivities", "next_page", page)
+	opts := fleetapi.ListActivitiesOptions{
+		ListOptions:    listOptions(ctx, d),
+		ActivityType:   qualString(d, "type"),
+		Query:          qualString(d, "query"),
+		StartCreatedAt: qualString(d, "start_created_at"),
+		EndCreatedAt:   qualString(d, "end_created_at"),
 	}
-
-	return nil, nil
+	return nil, client.ListActivities(tableContext(ctx, d), opts, streamItems[fleetapi.Activity](ctx, d))
 }
@@ -0,0 +1,55 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListActivities(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "page_size = 2")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_activity",
+		Columns: []string{"id", "type", "actor_id", "actor_email", "created_at", "details"},
+	})
+
+	assertColumn(t, rows, "id", 101, 102, 103, 104, 105)
+	assertColumn(t, rows, "actor_id", 1, 1, 2, nil, 1)
+	for _, r := range rows {
+		if r["id"] == int64(103) {
+			if details, _ := r["details"].(map[string]any); details["query_sql"] != "SELECT * FROM osquery_info;" {
+				t.Errorf("details = %v", r["details"])
+			}
+		}
+	}
+
+	// Every page is requested with page=0, continuing after the last id seen
+	requests := fake.Requests("activities")
+	wantAfter := []string{"", "102", "104"}
+	if len(requests) != len(wantAfter) {
+		t.Fatalf("requests to activities = %d, want %d: %v", len(requests), len(wantAfter), requests)
+	}
+	for i, params := range requests {
+		if params.Get("page") != "0" || params.Get("after") != wantAfter[i] {
+			t.Errorf("request %d params = %v, want page=0 and after=%q", i, params, wantAfter[i])
+		}
+	}
+}
+
+func TestListActivitiesType(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_activity",
+		Columns: []string{"id", "type"},
+		Quals:   map[string]any{"type": "created_team"},
+	})
+
+	assertColumn(t, rows, "id", 101, 105)
+	if params := fake.Requests("activities")[0]; params.Get("activity_type") != "created_team" {
+		t.Errorf("activity_type param not sent: %v", params)
+	}
+}
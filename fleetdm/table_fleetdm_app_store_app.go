@@ -2,54 +2,38 @@ package fleetdm
 
 import (
 	"context"
-	"net/url"
-	"strconv"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleetapi"
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
-// AppStoreApp represents an Apple App Store app in FleetDM.
-// Refer to: https://fleetdm.com/docs/rest-api/rest-api#list-apple-app-store-apps
-type AppStoreApp struct {
-	AppStoreID       string      `json:"app_store_id"`
-	Platform         string      `json:"platform"`
-	SelfService      bool        `json:"self_service"`
-	LabelsIncludeAny interface{} `json:"labels_include_any"`
-	LabelsExcludeAny interface{} `json:"labels_exclude_any"`
-	CreatedAt        *FleetTime  `json:"created_at"`
-	Categories       interface{} `json:"categories"`
-	DisplayName      *string     `json:"display_name"`
-	BundleIdentifier string      `json:"bundle_identifier"`
-	IconURL          string      `json:"icon_url"`
-	Name             string      `json:"name"`
-	LatestVersion    string      `json:"latest_version"`
-}
+// appStoreAppsMinVersion is the first Fleet release serving /software/app_store_apps.
+const appStoreAppsMinVersion = "4.53.0"
 
-// AppStoreAppWithTeam wraps an AppStoreApp with the team context it was queried for.
+// AppStoreAppWithTeam wraps a fleetapi.AppStoreApp with the team context it was queried for.
 type AppStoreAppWithTeam struct {
-	AppStoreApp
+	fleetapi.AppStoreApp
 	TeamID   uint   `json:"team_id"`
 	TeamName string `json:"team_name"`
 }
 
-// ListAppStoreAppsResponse is the expected structure for the list app store apps API call.
-type ListAppStoreAppsResponse struct {
-	AppStoreApps []AppStoreApp `json:"app_store_apps"`
-}
-
 func tableFleetdmAppStoreApp(ctx context.Context) *plugin.Table {
 	return &plugin.Table{
-		Name:        "fleetdm_app_store_app",
-		Description: "Apple App Store apps (VPP) from FleetDM. Lists apps available for install on teams. The API requires a team_id, so the plugin auto-discovers all teams and queries each one. Uses the /software/app_store_apps endpoint.",
+		Name:              "fleetdm_app_store_app",
+		Description:       "Apple App Store apps (VPP) from FleetDM. Lists apps available for install on teams. The API requires a team_id, so the plugin auto-discovers all teams and queries each one. Uses the /software/app_store_apps endpoint.",
+		GetMatrixItemFunc: serverMatrix,
 		List: &plugin.ListConfig{
 			Hydrate: listAppStoreApps,
+			Tags:    endpointTag(endpointFamilySoftware),
 			KeyColumns: []*plugin.KeyColumn{
 				{Name: "team_id", Require: plugin.Optional},
+				{Name: "server", Require: plugin.Optional}, // Query only this Fleet server
 			},
 		},
-		Columns: []*plugin.Column{
+		Columns: withServerColumn([]*plugin.Column{
 			// Core App Store app information
 			{Name: "app_store_id", Type: proto.ColumnType_STRING, Transform: transform.FromField("AppStoreID"), Description: "The Apple App Store ID of the app."},
 			{Name: "name", Type: proto.ColumnType_STRING, Description: "Name of the app."},
@@ -67,17 +51,25 @@ func tableFleetdmAppStoreApp(ctx context.Context) *plugin.Table {
 			// Team association
 			{Name: "team_id", Type: proto.ColumnType_INT, Transform: transform.FromField("TeamID"), Description: "The team ID this app was queried for. Set in WHERE clause to query a specific team."},
 			{Name: "team_name", Type: proto.ColumnType_STRING, Description: "The name of the team this app was queried for."},
-		},
+		}),
 	}
 }
 
 func listAppStoreApps(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
-	client, err := NewFleetDMClient(ctx, d.Connection)
+	client, err := getClient(ctx, d)
 	if err != nil {
 		plugin.Logger(ctx).Error("fleetdm_app_store_app.listAppStoreApps", "connection_error", err)
 		return nil, err
 	}
 
+	// App Store (VPP) apps are a Fleet Premium feature
+	if isFreeTier(ctx, d, "fleetdm_app_store_app.listAppStoreApps") {
+		return nil, nil
+	}
+	if err := checkMinVersion(ctx, d, "fleetdm_app_store_app", appStoreAppsMinVersion); err != nil {
+		return nil, err
+	}
+
 	// Build the list of teams to query.
 	// The /software/app_store_apps endpoint requires team_id.
 	type teamInfo struct {
@@ -95,29 +87,13 @@ func listAppStoreApps(ctx context.Context, d *plugin.QueryData, h *plugin.Hydrat
 		// Auto-discover all teams by calling GET /api/v1/fleet/teams.
 		plugin.Logger(ctx).Info("fleetdm_app_store_app.listAppStoreApps", "discovering_all_teams", true)
 
-		page := 0
-		perPage := 10000
-
-		for {
-			params := url.Values{}
-			params.Add("page", strconv.Itoa(page))
-			params.Add("per_page", strconv.Itoa(perPage))
-
-			var teamsResponse ListTeamsResponse
-			_, err := client.Get(ctx, "teams", params, &teamsResponse)
-			if err != nil {
-				plugin.Logger(ctx).Error("fleetdm_app_store_app.listAppStoreApps", "teams_api_error", err, "page", page)
-				return nil, err
-			}
-
-			for _, team := range teamsResponse.Teams {
-				teamsToQuery = append(teamsToQuery, teamInfo{ID: team.ID, Name: team.Name})
-			}
-
-			if len(teamsResponse.Teams) < perPage {
-				break
-			}
-			page++
+		teams, err := collectTeams(ctx, d, client)
+		if err != nil {
+			plugin.Logger(ctx).Error("fleetdm_app_store_app.listAppStoreApps", "teams_api_error", err)
+			return nil, err
+		}
+		for _, team := range teams {
+			teamsToQuery = append(teamsToQuery, teamInfo{ID: team.ID, Name: team.Name})
 		}
 
 		plugin.Logger(ctx).Info("fleetdm_app_store_app.listAppStoreApps", "total_teams_discovered", len(teamsToQuery))
@@ -125,11 +101,8 @@ func listAppStoreApps(ctx context.Context, d *plugin.QueryData, h *plugin.Hydrat
 
 	// For each team, query the app store apps endpoint.
 	for _, team := range teamsToQuery {
-		params := url.Values{}
-		params.Add("team_id", strconv.FormatUint(uint64(team.ID), 10))
-
-		var response ListAppStoreAppsResponse
-		_, err := client.Get(ctx, "software/app_store_apps", params, &response)
+		d.WaitForListRateLimit(ctx)
+		apps, err := client.ListAppStoreApps(tableContext(ctx, d), fleetapi.ListAppStoreAppsOptions{TeamID: team.ID})
 		if err != nil {
 			plugin.Logger(ctx).Error("fleetdm_app_store_app.listAppStoreApps", "api_error", err, "team_id", team.ID)
 			return nil, err
@@ -138,10 +111,10 @@ func listAppStoreApps(ctx context.Context, d *plugin.QueryData, h *plugin.Hydrat
 		plugin.Logger(ctx).Info("fleetdm_app_store_app.listAppStoreApps",
 			"team_id", team.ID,
 			"team_name", team.Name,
-			"apps_returned", len(response.AppStoreApps),
+			"apps_returned", len(apps),
 		)
 
-		for _, app := range response.AppStoreApps {
+		for _, app := range apps {
 			d.StreamListItem(ctx, AppStoreAppWithTeam{
 				AppStoreApp: app,
 				TeamID:      team.ID,
@@ -0,0 +1,50 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListAppStoreApps(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_app_store_app",
+		Columns: []string{"app_store_id", "name", "team_id", "team_name", "self_service", "categories"},
+	})
+
+	// Apps are listed once per discovered team
+	assertColumn(t, rows, "app_store_id", "497799835", "803453959", "1295203466")
+	assertColumn(t, rows, "team_name", "Workstations", "Workstations", "Servers")
+	assertRequests(t, fake, "teams", 1)
+	assertRequests(t, fake, "software/app_store_apps", 2)
+}
+
+func TestListAppStoreAppsForTeam(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_app_store_app",
+		Columns: []string{"app_store_id", "team_id"},
+		Quals:   map[string]any{"team_id": 2},
+	})
+
+	assertColumn(t, rows, "app_store_id", "1295203466")
+	assertColumn(t, rows, "team_id", 2)
+	assertRequests(t, fake, "teams", 0)
+}
+
+func TestListAppStoreAppsOnFreeTier(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{Tier: fleettest.TierFree})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{Table: "fleetdm_app_store_app", Columns: []string{"app_store_id"}})
+
+	if len(rows) != 0 {
+		t.Errorf("got %d rows on the free tier, want 0", len(rows))
+	}
+	assertRequests(t, fake, "software/app_store_apps", 0)
+}
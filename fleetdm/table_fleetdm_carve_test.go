@@ -0,0 +1,24 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListCarves(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_carve",
+		Columns: []string{"id", "host_id", "carve_size", "expired", "error", "created_at"},
+	})
+
+	assertColumn(t, rows, "host_id", 1, 3)
+	assertColumn(t, rows, "carve_size", 7340032, 1024)
+	assertColumn(t, rows, "expired", false, true)
+	assertColumn(t, rows, "error", nil, "carve expired")
+	assertColumn(t, rows, "created_at", "2024-05-10T10:00:00Z", "2024-04-01T10:00:00Z")
+	assertRequests(t, fake, "carves", 1)
+}
@@ -0,0 +1,146 @@
+package fleetdm
+
+import (
+	"context"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleetapi"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// ConfigurationProfileWithTeam wraps a fleetapi.ConfigurationProfile with the team it was queried for.
+type ConfigurationProfileWithTeam struct {
+	fleetapi.ConfigurationProfile
+	TeamName string `json:"team_name"`
+}
+
+func tableFleetdmConfigurationProfile(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:              "fleetdm_configuration_profile",
+		Description:       "Apple and Windows MDM configuration profiles from FleetDM, with the number of hosts in each delivery status. The API lists one team at a time, so the plugin queries hosts without a team and each discovered team. Uses the /configuration_profiles endpoint.",
+		GetMatrixItemFunc: serverMatrix,
+		List: &plugin.ListConfig{
+			Hydrate: listConfigurationProfiles,
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "team_id", Require: plugin.Optional}, // Query only this team, 0 for hosts without a team
+				{Name: "server", Require: plugin.Optional},  // Query only this Fleet server
+			},
+		},
+		HydrateConfig: []plugin.HydrateConfig{
+			{
+				Func: getConfigurationProfileStatus,
+				// Servers without the status endpoint, or a profile deleted mid-scan, leave the counts null
+				IgnoreConfig: &plugin.IgnoreConfig{
+					ShouldIgnoreErrorFunc: isNotFoundError,
+				},
+			},
+		},
+		Columns: withServerColumn([]*plugin.Column{
+			// Core profile information
+			{Name: "profile_uuid", Type: proto.ColumnType_STRING, Transform: transform.FromField("ProfileUUID"), Description: "UUID of the configuration profile."},
+			{Name: "name", Type: proto.ColumnType_STRING, Description: "Name of the configuration profile."},
+			{Name: "platform", Type: proto.ColumnType_STRING, Description: "Platform of the configuration profile (e.g., 'darwin', 'windows')."},
+			{Name: "identifier", Type: proto.ColumnType_STRING, Description: "PayloadIdentifier of the profile. Only set for Apple profiles."},
+			{Name: "checksum", Type: proto.ColumnType_STRING, Description: "Base64-encoded MD5 checksum of the profile contents."},
+			{Name: "labels_include_all", Type: proto.ColumnType_JSON, Description: "Labels a host must all be a member of to receive the profile."},
+			{Name: "labels_include_any", Type: proto.ColumnType_JSON, Description: "Labels a host must be a member of at least one of to receive the profile."},
+			{Name: "labels_exclude_any", Type: proto.ColumnType_JSON, Description: "Labels whose member hosts do not receive the profile."},
+			{Name: "created_at", Type: proto.ColumnType_TIMESTAMP, Transform: transform.FromField("CreatedAt").Transform(flexibleTimeTransform), Description: "Timestamp when the profile was added."},
+			{Name: "uploaded_at", Type: proto.ColumnType_TIMESTAMP, Transform: transform.FromField("UploadedAt").Transform(flexibleTimeTransform), Description: "Timestamp when the profile contents were last uploaded."},
+
+			// Host counts from /configuration_profiles/:profile_uuid/status
+			{Name: "verified_count", Type: proto.ColumnType_INT, Hydrate: getConfigurationProfileStatus, Transform: transform.FromField("Verified"), Description: "Number of hosts where Fleet verified the profile is installed."},
+			{Name: "verifying_count", Type: proto.ColumnType_INT, Hydrate: getConfigurationProfileStatus, Transform: transform.FromField("Verifying"), Description: "Number of hosts which acknowledged the profile, pending verification by Fleet."},
+			{Name: "pending_count", Type: proto.ColumnType_INT, Hydrate: getConfigurationProfileStatus, Transform: transform.FromField("Pending"), Description: "Number of hosts where the profile is waiting to be installed or removed."},
+			{Name: "failed_count", Type: proto.ColumnType_INT, Hydrate: getConfigurationProfileStatus, Transform: transform.FromField("Failed"), Description: "Number of hosts where the profile failed to install or be removed."},
+
+			// Team association
+			{Name: "team_id", Type: proto.ColumnType_INT, Transform: transform.FromField("TeamID"), Description: "The team ID this profile was queried for, 0 for hosts without a team. Set in WHERE clause to query a specific team."},
+			{Name: "team_name", Type: proto.ColumnType_STRING, Description: "The name of the team this profile was queried for."},
+		}),
+	}
+}
+
+func listConfigurationProfiles(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	client, err := getClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("fleetdm_configuration_profile.listConfigurationProfiles", "connection_error", err)
+		return nil, err
+	}
+
+	// Build the list of teams to query.
+	// The /configuration_profiles endpoint lists a single team, or hosts without a team.
+	type teamInfo struct {
+		ID   uint
+		Name string
+	}
+	var teamsToQuery []teamInfo
+
+	if d.EqualsQuals["team_id"] != nil {
+		// User specified a team_id in the WHERE clause — only query that team.
+		teamID := uint(d.EqualsQuals["team_id"].GetInt64Value())
+		teamsToQuery = append(teamsToQuery, teamInfo{ID: teamID, Name: ""})
+		plugin.Logger(ctx).Info("fleetdm_configuration_profile.listConfigurationProfiles", "using_specific_team_id", teamID)
+	} else {
+		teamsToQuery = append(teamsToQuery, teamInfo{ID: 0, Name: "No team"})
+
+		// Teams are a Fleet Premium feature, free tier servers only have hosts without a team
+		if info := detectedServerInfo(ctx, d); info == nil || info.IsPremium() {
+			plugin.Logger(ctx).Info("fleetdm_configuration_profile.listConfigurationProfiles", "discovering_all_teams", true)
+
+			teams, err := collectTeams(ctx, d, client)
+			if err != nil {
+				plugin.Logger(ctx).Error("fleetdm_configuration_profile.listConfigurationProfiles", "teams_api_error", err)
+				return nil, err
+			}
+			for _, team := range teams {
+				teamsToQuery = append(teamsToQuery, teamInfo{ID: team.ID, Name: team.Name})
+			}
+		}
+
+		plugin.Logger(ctx).Info("fleetdm_configuration_profile.listConfigurationProfiles", "total_teams_discovered", len(teamsToQuery))
+	}
+
+	// For each team, query the configuration profiles endpoint.
+	for _, team := range teamsToQuery {
+		// The options are built per team, so the limit covers only the rows still missing
+		opts := fleetapi.ListConfigurationProfilesOptions{ListOptions: listOptions(ctx, d), TeamID: team.ID}
+		err := client.ListConfigurationProfiles(tableContext(ctx, d), opts, func(profile fleetapi.ConfigurationProfile) bool {
+			d.StreamListItem(ctx, ConfigurationProfileWithTeam{
+				ConfigurationProfile: profile,
+				TeamName:             team.Name,
+			})
+			return d.RowsRemaining(ctx) != 0
+		})
+		if err != nil {
+			plugin.Logger(ctx).Error("fleetdm_configuration_profile.listConfigurationProfiles", "api_error", err, "team_id", team.ID)
+			return nil, err
+		}
+		if d.RowsRemaining(ctx) == 0 {
+			plugin.Logger(ctx).Debug("fleetdm_configuration_profile.listConfigurationProfiles", "limit_reached", true)
+			return nil, nil
+		}
+	}
+
+	plugin.Logger(ctx).Info("fleetdm_configuration_profile.listConfigurationProfiles", "list_configuration_profiles_completed", true)
+	return nil, nil
+}
+
+func getConfigurationProfileStatus(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	profile := h.Item.(ConfigurationProfileWithTeam)
+
+	client, err := getClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("fleetdm_configuration_profile.getConfigurationProfileStatus", "connection_error", err, "profile_uuid", profile.ProfileUUID)
+		return nil, err
+	}
+
+	status, err := client.GetConfigurationProfileStatus(tableContext(ctx, d), profile.ProfileUUID)
+	if err != nil {
+		plugin.Logger(ctx).Error("fleetdm_configuration_profile.getConfigurationProfileStatus", "api_error", err, "profile_uuid", profile.ProfileUUID)
+		return nil, err
+	}
+	return *status, nil
+}
@@ -0,0 +1,95 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListConfigurationProfiles(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_configuration_profile",
+		Columns: []string{"profile_uuid", "name", "team_id", "team_name", "verified_count", "verifying_count", "pending_count", "failed_count"},
+	})
+
+	// Profiles are listed for hosts without a team and once per discovered team
+	assertColumn(t, rows, "name", "Screen saver", "Windows Defender settings", "Disk encryption", "Corporate Wi-Fi", "Passcode policy")
+	assertColumn(t, rows, "team_name", "No team", "No team", "Workstations", "Workstations", "Workstations")
+	assertColumn(t, rows, "team_id", 0, 0, 1, 1, 1)
+	assertColumn(t, rows, "failed_count", 0, 0, 0, 1, 0)
+	assertColumn(t, rows, "verifying_count", 0, 1, 0, 0, 0)
+	assertRequests(t, fake, "teams", 1)
+	assertRequests(t, fake, "configuration_profiles", 3)
+	assertRequests(t, fake, "configuration_profiles/"+wifiProfileUUID+"/status", 1)
+}
+
+func TestListConfigurationProfilesForTeam(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_configuration_profile",
+		Columns: []string{"name", "team_id", "identifier", "checksum", "labels_include_any"},
+		Quals:   map[string]any{"team_id": 1},
+	})
+
+	assertColumn(t, rows, "name", "Disk encryption", "Corporate Wi-Fi", "Passcode policy")
+	assertColumn(t, rows, "team_id", 1, 1, 1)
+	assertRequests(t, fake, "teams", 0)
+	if params := fake.Requests("configuration_profiles")[0]; params.Get("team_id") != "1" {
+		t.Errorf("request params = %v", params)
+	}
+	for _, r := range rows {
+		if r["name"] == "Corporate Wi-Fi" && (r["identifier"] != "com.example.wifi" || r["labels_include_any"] == nil) {
+			t.Errorf("unexpected Wi-Fi row: %v", r)
+		}
+	}
+}
+
+func TestListConfigurationProfilesWithoutTeam(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_configuration_profile",
+		Columns: []string{"name", "platform"},
+		Quals:   map[string]any{"team_id": 0},
+	})
+
+	assertColumn(t, rows, "name", "Screen saver", "Windows Defender settings")
+	assertColumn(t, rows, "platform", "darwin", "windows")
+	assertRequests(t, fake, "teams", 0)
+	if params := fake.Requests("configuration_profiles")[0]; params.Has("team_id") {
+		t.Errorf("team_id 0 should not be sent: %v", params)
+	}
+}
+
+func TestListConfigurationProfilesOnFreeTier(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{Tier: fleettest.TierFree})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{Table: "fleetdm_configuration_profile", Columns: []string{"name"}})
+
+	// Free tier servers have no teams, only hosts without a team
+	assertColumn(t, rows, "name", "Screen saver", "Windows Defender settings")
+	assertRequests(t, fake, "teams", 0)
+}
+
+func TestListConfigurationProfilesWithoutStatusEndpoint(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	fake.InjectError("configuration_profiles/"+wifiProfileUUID+"/status", 404, 0)
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_configuration_profile",
+		Columns: []string{"name", "failed_count"},
+		Quals:   map[string]any{"team_id": 1},
+	})
+
+	// The profile is still listed, without counts
+	assertColumn(t, rows, "name", "Disk encryption", "Corporate Wi-Fi", "Passcode policy")
+	assertColumn(t, rows, "failed_count", 0, nil, 0)
+}
@@ -0,0 +1,42 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListFleetMaintainedApps(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "page_size = 2")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_fleet_maintained_app",
+		Columns: []string{"id", "name", "slug", "version", "software_title_id", "categories"},
+	})
+
+	assertColumn(t, rows, "slug", "1password/darwin", "google-chrome/darwin", "zoom/windows")
+	assertColumn(t, rows, "software_title_id", nil, 11, nil)
+	assertRequests(t, fake, "software/fleet_maintained_apps", 2)
+}
+
+func TestListFleetMaintainedAppsOnFreeTier(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{Tier: fleettest.TierFree})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{Table: "fleetdm_fleet_maintained_app", Columns: []string{"id"}})
+
+	if len(rows) != 0 {
+		t.Errorf("got %d rows on the free tier, want 0", len(rows))
+	}
+	assertRequests(t, fake, "software/fleet_maintained_apps", 0)
+}
+
+func TestListFleetMaintainedAppsOnOldServer(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{Version: "4.50.0"})
+	conn := newTestConnection(t, fake, "")
+
+	_, err := conn.execute(testQuery{Table: "fleetdm_fleet_maintained_app", Columns: []string{"id"}})
+
+	assertErrorContains(t, err, "fleetdm_fleet_maintained_app requires Fleet 4.57.0 or later, but the server runs 4.50.0")
+}
@@ -2,153 +2,22 @@ package fleetdm
 
 import (
 	"context"
-	"encoding/json" // For json.RawMessage
+	"encoding/json"
 	"fmt"
-	"net/url"
-	"strconv"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleetapi"
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
-// HostUser represents a user on a specific host.
-type HostUser struct {
-	UID       int    `json:"uid"`
-	Username  string `json:"username"`
-	Type      string `json:"type"`
-	Groupname string `json:"groupname"`
-	Shell     string `json:"shell"`
-}
-
-// HostPolicy represents a policy's status on a specific host.
-type HostPolicy struct {
-	ID                    uint      `json:"id"`
-	Name                  string    `json:"name"`
-	Query                 string    `json:"query"`
-	Critical              bool      `json:"critical"`
-	Description           string    `json:"description"`
-	AuthorID              *uint     `json:"author_id"`
-	AuthorName            string    `json:"author_name"`
-	AuthorEmail           string    `json:"author_email"`
-	TeamID                *uint     `json:"team_id"`
-	Resolution            string    `json:"resolution"`
-	Platform              string    `json:"platform"`
-	CalendarEventsEnabled bool      `json:"calendar_events_enabled"`
-	CreatedAt             FleetTime `json:"created_at"`
-	UpdatedAt             FleetTime `json:"updated_at"`
-	Response              string    `json:"response"` // e.g., "pass", "fail"
-}
-
-// HostLabel represents a label applied to a specific host.
-type HostLabel struct {
-	ID                  uint      `json:"id"`
-	CreatedAt           FleetTime `json:"created_at"`
-	UpdatedAt           FleetTime `json:"updated_at"`
-	Name                string    `json:"name"`
-	Description         string    `json:"description"`
-	Query               string    `json:"query"`
-	Platform            string    `json:"platform"`
-	LabelType           string    `json:"label_type"`
-	LabelMembershipType string    `json:"label_membership_type"`
-	AuthorID            *uint     `json:"author_id,omitempty"`
-}
-
-// HostIssues represents the 'issues' object for a host.
-type HostIssues struct {
-	FailingPoliciesCount         int `json:"failing_policies_count"`
-	CriticalVulnerabilitiesCount int `json:"critical_vulnerabilities_count"`
-	TotalIssuesCount             int `json:"total_issues_count"`
-}
-
-// HostMDM represents the 'mdm' object for a host.
-type HostMDM struct {
-	EnrollmentStatus       string  `json:"enrollment_status"`
-	DEPProfileError        bool    `json:"dep_profile_error"`
-	ServerURL              *string `json:"server_url"`
-	Name                   *string `json:"name"`
-	EncryptionKeyAvailable bool    `json:"encryption_key_available"`
-	ConnectedToFleet       *bool   `json:"connected_to_fleet"`
-}
-
-// DeviceMappingItem represents an item within the device_mapping array.
-type DeviceMappingItem struct {
-	Email  string `json:"email"`
-	Source string `json:"source"`
-}
-
-// Host represents a FleetDM host.
-// Refer to: https://fleetdm.com/docs/rest-api/rest-api#list-hosts
-type Host struct {
-	ID                          int                 `json:"id"`
-	CreatedAt                   FleetTime           `json:"created_at"`
-	UpdatedAt                   FleetTime           `json:"updated_at"`
-	SoftwareUpdatedAt           *FleetTime          `json:"software_updated_at"`
-	DetailUpdatedAt             FleetTime           `json:"detail_updated_at"`
-	LabelUpdatedAt              FleetTime           `json:"label_updated_at"`
-	PolicyUpdatedAt             FleetTime           `json:"policy_updated_at"`
-	LastEnrolledAt              FleetTime           `json:"last_enrolled_at"`
-	SeenTime                    FleetTime           `json:"seen_time"`
-	RefetchRequested            bool                `json:"refetch_requested"`
-	UUID                        string              `json:"uuid"`
-	Hostname                    string              `json:"hostname"`
-	DisplayName                 string              `json:"display_name"`
-	DisplayText                 string              `json:"display_text"`
-	ComputerName                string              `json:"computer_name"`
-	Platform                    string              `json:"platform"`
-	PlatformLike                string              `json:"platform_like"`
-	OsVersion                   string              `json:"os_version"`
-	Build                       string              `json:"build"`
-	CodeName                    string              `json:"code_name"`
-	Uptime                      int64               `json:"uptime"` // Nanoseconds
-	Memory                      int64               `json:"memory"` // bytes
-	CPUType                     string              `json:"cpu_type"`
-	CPUSubtype                  string              `json:"cpu_subtype"`
-	CPUBrand                    string              `json:"cpu_brand"`
-	CPUPhysicalCores            int                 `json:"cpu_physical_cores"`
-	CPULogicalCores             int                 `json:"cpu_logical_cores"`
-	HardwareVendor              string              `json:"hardware_vendor"`
-	HardwareModel               string              `json:"hardware_model"`
-	HardwareVersion             string              `json:"hardware_version"`
-	HardwareSerial              string              `json:"hardware_serial"`
-	PrimaryIP                   string              `json:"primary_ip"`
-	PrimaryMac                  string              `json:"primary_mac"`
-	PublicIP                    string              `json:"public_ip"`
-	OrbitVersion                *string             `json:"orbit_version"`
-	FleetDesktopVersion         *string             `json:"fleet_desktop_version"`
-	ScriptsEnabled              *bool               `json:"scripts_enabled"`
-	OsqueryVersion              *string             `json:"osquery_version"`
-	TeamID                      *int                `json:"team_id"`
-	TeamName                    *string             `json:"team_name"`
-	DistributedInterval         *int                `json:"distributed_interval"`
-	ConfigTLSRefresh            *int                `json:"config_tls_refresh"`
-	LoggerTLSPeriod             *int                `json:"logger_tls_period"`
-	PackStats                   *json.RawMessage    `json:"pack_stats"`
-	GigsDiskSpaceAvailable      float64             `json:"gigs_disk_space_available"`
-	PercentDiskSpaceAvailable   float64             `json:"percent_disk_space_available"`
-	GigsTotalDiskSpace          float64             `json:"gigs_total_disk_space"`
-	Status                      string              `json:"status"`
-	Issues                      *HostIssues         `json:"issues"`
-	MDM                         *HostMDM            `json:"mdm"`
-	RefetchCriticalQueriesUntil *FleetTime          `json:"refetch_critical_queries_until"`
-	LastRestartedAt             *FleetTime          `json:"last_restarted_at"`
-	Users                       []HostUser          `json:"users,omitempty"`
-	Policies                    []HostPolicy        `json:"policies,omitempty"`
-	Labels                      []HostLabel         `json:"labels,omitempty"`
-	DeviceMapping               []DeviceMappingItem `json:"device_mapping,omitempty"` // Updated from *json.RawMessage
-}
-
-// ListHostsResponse is the expected structure for the list hosts API call.
-type ListHostsResponse struct {
-	Hosts []Host `json:"hosts"`
-}
-
 // Custom transform to ensure MDM struct is marshalled to JSON string
 func mdmToJSONString(ctx context.Context, d *transform.TransformData) (interface{}, error) {
 	if d.Value == nil {
 		return nil, nil
 	}
-	mdmData, ok := d.Value.(*HostMDM)
+	mdmData, ok := d.Value.(*fleetapi.HostMDM)
 	if !ok {
 		return nil, fmt.Errorf("mdmToJSONString: type assertion to *HostMDM failed for type %T", d.Value)
 	}
@@ -182,10 +51,12 @@ func arrayOrObjectToJSONString(ctx context.Context, d *transform.TransformData)
 
 func tableFleetdmHost(ctx context.Context) *plugin.Table {
 	return &plugin.Table{
-		Name:        "fleetdm_host",
-		Description: "Information about hosts managed by FleetDM.",
+		Name:              "fleetdm_host",
+		Description:       "Information about hosts managed by FleetDM.",
+		GetMatrixItemFunc: serverMatrix,
 		List: &plugin.ListConfig{
 			Hydrate: listHosts,
+			Tags:    endpointTag(endpointFamilyHosts),
 			KeyColumns: []*plugin.KeyColumn{
 				{Name: "query", Require: plugin.Optional},                 // Search by hostname, serial, uuid, ip, email
 				{Name: "team_id", Require: plugin.Optional},               // Filter by team (Fleet Premium)
@@ -198,9 +69,11 @@ func tableFleetdmHost(ctx context.Context) *plugin.Table {
 				{Name: "policy_response", Require: plugin.Optional},       // Requires policy_id. 'passing' or 'failing'
 				{Name: "mdm_enrollment_status", Require: plugin.Optional}, // Filter by MDM enrollment status
 				{Name: "low_disk_space", Require: plugin.Optional},        // Filter by low disk space threshold (Fleet Premium)
+				{Name: "cache_bypass", Require: plugin.Optional},          // Skip the response cache
+				{Name: "server", Require: plugin.Optional},                // Query only this Fleet server
 			},
 		},
-		Columns: []*plugin.Column{
+		Columns: withServerColumn([]*plugin.Column{
 			// Core Identification
 			{Name: "id", Type: proto.ColumnType_INT, Description: "The unique ID of the host."},
 			{Name: "hostname", Type: proto.ColumnType_STRING, Description: "The hostname of the host."},
@@ -265,10 +138,10 @@ func tableFleetdmHost(ctx context.Context) *plugin.Table {
 			// Issues, MDM, Users, Policies, Labels
 			{Name: "issues", Type: proto.ColumnType_JSON, Transform: transform.FromField("Issues").Transform(arrayOrObjectToJSONString), Description: "Host issues summary (failing policies, vulnerabilities)."},
 			{Name: "mdm", Type: proto.ColumnType_JSON, Transform: transform.FromField("MDM").Transform(mdmToJSONString), Description: "Mobile Device Management (MDM) information for the host."},
-			{Name: "users", Type: proto.ColumnType_JSON, Transform: transform.FromField("Users").Transform(arrayOrObjectToJSONString), Description: "Users on this host (requires populate_users=true)."},
-			{Name: "policies", Type: proto.ColumnType_JSON, Transform: transform.FromField("Policies").Transform(arrayOrObjectToJSONString), Description: "Policy compliance status for this host (requires populate_policies=true)."},
-			{Name: "labels", Type: proto.ColumnType_JSON, Transform: transform.FromField("Labels").Transform(arrayOrObjectToJSONString), Description: "Labels applied to this host (requires populate_labels=true)."},
-			{Name: "device_mapping", Type: proto.ColumnType_JSON, Transform: transform.FromField("DeviceMapping").Transform(arrayOrObjectToJSONString), Description: "Device mapping information (requires device_mapping=true)."},
+			{Name: "users", Type: proto.ColumnType_JSON, Transform: transform.FromField("Users").Transform(arrayOrObjectToJSONString), Description: "Users on this host (requested with populate_users=true only when selected)."},
+			{Name: "policies", Type: proto.ColumnType_JSON, Transform: transform.FromField("Policies").Transform(arrayOrObjectToJSONString), Description: "Policy compliance status for this host (requested with populate_policies=true only when selected)."},
+			{Name: "labels", Type: proto.ColumnType_JSON, Transform: transform.FromField("Labels").Transform(arrayOrObjectToJSONString), Description: "Labels applied to this host (requested with populate_labels=true only when selected)."},
+			{Name: "device_mapping", Type: proto.ColumnType_JSON, Transform: transform.FromField("DeviceMapping").Transform(arrayOrObjectToJSONString), Description: "Device mapping information (requested with device_mapping=true only when selected)."},
 
 			// Query parameters that can be used for filtering (key columns)
 			{Name: "query", Type: proto.ColumnType_STRING, Transform: transform.FromQual("query"), Description: "Search query keywords. Searchable fields include hostname, hardware_serial, uuid, ipv4, and email. Set in WHERE clause."},
@@ -280,97 +153,73 @@ func tableFleetdmHost(ctx context.Context) *plugin.Table {
 			{Name: "policy_response", Type: proto.ColumnType_STRING, Transform: transform.FromQual("policy_response"), Description: "Filter by policy response. Requires policy_id. Options: 'passing', 'failing'. Set in WHERE clause."},
 			{Name: "mdm_enrollment_status", Type: proto.ColumnType_STRING, Transform: transform.FromQual("mdm_enrollment_status"), Description: "Filter by MDM enrollment status: 'manual', 'automatic', 'enrolled', 'pending', 'unenrolled'. Set in WHERE clause."},
 			{Name: "low_disk_space", Type: proto.ColumnType_INT, Transform: transform.FromQual("low_disk_space"), Description: "Filter hosts with less than N GB free disk space (1-100, Fleet Premium). Set in WHERE clause."},
-		},
+			{Name: "cache_bypass", Type: proto.ColumnType_BOOL, Transform: transform.FromQual("cache_bypass"), Description: "Set to true to skip the response cache configured with cache_dir and fetch fresh data. Set in WHERE clause."},
+		}),
 	}
 }
 
-func addHostPopulationParams(params url.Values) {
-	params.Add("device_mapping", "true")
-	params.Add("populate_policies", "true")
-	params.Add("populate_users", "true")
-	params.Add("populate_labels", "true")
+// hostPopulationParams maps the JSON columns Fleet only computes on request to
+// the param which populates them.
+var hostPopulationParams = map[string]string{
+	"device_mapping": "device_mapping",
+	"policies":       "populate_policies",
+	"users":          "populate_users",
+	"labels":         "populate_labels",
+}
+
+// hostPopulateParams returns the populate params for the optional host fields
+// the query selects. Fleet computes each of them for every host returned, so
+// `select hostname from fleetdm_host` should not ask for policies or labels.
+func hostPopulateParams(d *plugin.QueryData) []string {
+	var populate []string
+	for _, column := range d.QueryContext.Columns {
+		if param, ok := hostPopulationParams[column]; ok {
+			populate = append(populate, param)
+		}
+	}
+	return populate
 }
 
 // listHosts fetches a list of hosts from the FleetDM API.
 func listHosts(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
-	client, err := NewFleetDMClient(ctx, d.Connection)
+	ctx = cacheBypassContext(ctx, d)
+	client, err := getClient(ctx, d)
 	if err != nil {
 		plugin.Logger(ctx).Error("fleetdm_host.listHosts", "connection_error", err)
 		return nil, err
 	}
 
-	page := 0
-	perPage := 100
-
-	for {
-		params := url.Values{}
-		params.Add("page", strconv.Itoa(page))
-		params.Add("per_page", strconv.Itoa(perPage))
-		params.Add("order_key", "id")
-		params.Add("order_direction", "desc") // Get latest hosts first, or 'asc' for consistent paging
-
-		addHostPopulationParams(params)
-
-		// Apply key column filters
-		if d.EqualsQuals["query"] != nil {
-			params.Add("query", d.EqualsQuals["query"].GetStringValue())
-		}
-		if d.EqualsQuals["team_id"] != nil {
-			params.Add("team_id", strconv.FormatInt(d.EqualsQuals["team_id"].GetInt64Value(), 10))
-		}
-		if d.EqualsQuals["status"] != nil {
-			params.Add("status", d.EqualsQuals["status"].GetStringValue())
-		}
-		if d.EqualsQuals["os_version_id"] != nil {
-			params.Add("os_version_id", strconv.FormatInt(d.EqualsQuals["os_version_id"].GetInt64Value(), 10))
-		}
-		if d.EqualsQuals["vulnerability"] != nil {
-			params.Add("vulnerability", d.EqualsQuals["vulnerability"].GetStringValue())
-		}
-		if d.EqualsQuals["software_version_id"] != nil {
-			params.Add("software_version_id", strconv.FormatInt(d.EqualsQuals["software_version_id"].GetInt64Value(), 10))
-		}
-		if d.EqualsQuals["software_title_id"] != nil {
-			params.Add("software_title_id", strconv.FormatInt(d.EqualsQuals["software_title_id"].GetInt64Value(), 10))
-		}
-		if d.EqualsQuals["policy_id"] != nil {
-			params.Add("policy_id", strconv.FormatInt(d.EqualsQuals["policy_id"].GetInt64Value(), 10))
-		}
-		if d.EqualsQuals["policy_response"] != nil {
-			params.Add("policy_response", d.EqualsQuals["policy_response"].GetStringValue())
-		}
-		if d.EqualsQuals["mdm_enrollment_status"] != nil {
-			params.Add("mdm_enrollment_status", d.EqualsQuals["mdm_enrollment_status"].GetStringValue())
-		}
-		if d.EqualsQuals["low_disk_space"] != nil {
-			params.Add("low_disk_space", strconv.FormatInt(d.EqualsQuals["low_disk_space"].GetInt64Value(), 10))
-		}
-
-		plugin.Logger(ctx).Debug("fleetdm_host.listHosts", "request_params", params.Encode())
-
-		var response ListHostsResponse
-		_, err := client.Get(ctx, "hosts", params, &response)
-		if err != nil {
-			plugin.Logger(ctx).Error("fleetdm_host.listHosts", "api_error", err, "page", page, "params", params.Encode())
-			return nil, err
-		}
-
-		for _, host := range response.Hosts {
-			d.StreamListItem(ctx, host)
-			if d.RowsRemaining(ctx) == 0 {
-				plugin.Logger(ctx).Debug("fleetdm_host.listHosts", "limit_reached", true)
-				return nil, nil
-			}
-		}
-
-		if len(response.Hosts) < perPage {
-			plugin.Logger(ctx).Debug("fleetdm_host.listHosts", "end_of_results", true, "hosts_count_on_page", len(response.Hosts))
-			break
-		}
+	if err := checkPremiumQuals(ctx, d, "fleetdm_host", "low_disk_space"); err != nil {
+		return nil, err
+	}
 
-		page++
-		plugin.Logger(ctx).Debug("fleetdm_host.listHosts", "next_page", page)
+	opts := fleetapi.ListHostsOptions{
+		ListOptions:         listOptions(ctx, d),
+		OrderDirection:      "desc", // Get latest hosts first
+		Query:               qualString(d, "query"),
+		Status:              qualString(d, "status"),
+		TeamID:              qualUint(d, "team_id"),
+		OSVersionID:         qualUint(d, "os_version_id"),
+		Vulnerability:       qualString(d, "vulnerability"),
+		SoftwareVersionID:   qualUint(d, "software_version_id"),
+		SoftwareTitleID:     qualUint(d, "software_title_id"),
+		PolicyID:            qualUint(d, "policy_id"),
+		PolicyResponse:      qualString(d, "policy_response"),
+		MDMEnrollmentStatus: qualString(d, "mdm_enrollment_status"),
+		LowDiskSpace:        qualInt(d, "low_disk_space"),
+		Populate:            hostPopulateParams(d),
 	}
+	return nil, client.ListHosts(tableContext(ctx, d), opts, streamItems[fleetapi.Host](ctx, d))
+}
 
-	return nil, nil
+// collectHosts returns every host, for tables whose endpoints are queried per host.
+func collectHosts(ctx context.Context, d *plugin.QueryData, client *fleetapi.FleetDMClient) ([]fleetapi.Host, error) {
+	opts := fleetapi.ListHostsOptions{ListOptions: listOptions(ctx, d), OrderDirection: "asc"}
+	opts.Limit = 0 // The query's limit applies to the table's rows, not to the hosts
+	var hosts []fleetapi.Host
+	err := client.ListHosts(tableContext(ctx, d), opts, func(host fleetapi.Host) bool {
+		hosts = append(hosts, host)
+		return true
+	})
+	return hosts, err
 }
@@ -4,159 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/url"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleetapi"
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
-// --- New and Updated Structs based on detailed JSON sample ---
-
-// HostMDMDetail represents the rich 'mdm' object.
-type HostMDMDetail struct {
-	EncryptionKeyAvailable bool             `json:"encryption_key_available"`
-	EnrollmentStatus       string           `json:"enrollment_status"`
-	Name                   *string          `json:"name"`
-	ConnectedToFleet       *bool            `json:"connected_to_fleet"`
-	ServerURL              *string          `json:"server_url"`
-	DeviceStatus           string           `json:"device_status"`
-	PendingAction          string           `json:"pending_action"`
-	MacOSSettings          *json.RawMessage `json:"macos_settings"`
-	MacOSSetup             *json.RawMessage `json:"macos_setup"`
-	OsSettings             *json.RawMessage `json:"os_settings"`
-	Profiles               *json.RawMessage `json:"profiles"`
-}
-
-// HostBattery represents a battery on a host.
-type HostBattery struct {
-	CycleCount int    `json:"cycle_count"`
-	Health     string `json:"health"`
-}
-
-// HostGeometry represents the geometry part of geolocation.
-type HostGeometry struct {
-	Type        string    `json:"type"`
-	Coordinates []float64 `json:"coordinates"`
-}
-
-// HostGeolocation represents the geolocation of a host.
-type HostGeolocation struct {
-	CountryISO string        `json:"country_iso"`
-	CityName   string        `json:"city_name"`
-	Geometry   *HostGeometry `json:"geometry"`
-}
-
-// HostMaintenanceWindow represents a configured maintenance window.
-type HostMaintenanceWindow struct {
-	StartsAt FleetTime `json:"starts_at"`
-	Timezone string    `json:"timezone"`
-}
-
-// HostOtherEmail represents an email entry for an end user.
-type HostOtherEmail struct {
-	Email  string `json:"email"`
-	Source string `json:"source"`
-}
-
-// HostEndUser represents an end user associated with a device.
-type HostEndUser struct {
-	IdpInfoUpdatedAt FleetTime        `json:"idp_info_updated_at"`
-	IdpID            string           `json:"idp_id"`
-	IdpUsername      string           `json:"idp_username"`
-	IdpFullName      string           `json:"idp_full_name"`
-	IdpGroups        []string         `json:"idp_groups"`
-	OtherEmails      []HostOtherEmail `json:"other_emails"`
-}
-
-// HostSoftware represents a software item on a specific host.
-type HostSoftware struct {
-	ID               uint             `json:"id"`
-	Name             string           `json:"name"`
-	Version          string           `json:"version"`
-	Source           string           `json:"source"`
-	Browser          string           `json:"browser"`
-	BundleIdentifier string           `json:"bundle_identifier"`
-	LastOpenedAt     *FleetTime       `json:"last_opened_at"`
-	GeneratedCPE     string           `json:"generated_cpe"`
-	Vulnerabilities  *json.RawMessage `json:"vulnerabilities"`
-	InstalledPaths   []string         `json:"installed_paths"`
-}
-
-// HostDetail represents the full, rich host object from GET /hosts/:id
-type HostDetail struct {
-	ID                          int                    `json:"id"`
-	CreatedAt                   FleetTime              `json:"created_at"`
-	UpdatedAt                   FleetTime              `json:"updated_at"`
-	SoftwareUpdatedAt           FleetTime              `json:"software_updated_at"`
-	DetailUpdatedAt             FleetTime              `json:"detail_updated_at"`
-	LabelUpdatedAt              FleetTime              `json:"label_updated_at"`
-	PolicyUpdatedAt             FleetTime              `json:"policy_updated_at"`
-	LastEnrolledAt              FleetTime              `json:"last_enrolled_at"`
-	LastMdmCheckedInAt          FleetTime              `json:"last_mdm_checked_in_at"`
-	LastMdmEnrolledAt           FleetTime              `json:"last_mdm_enrolled_at"`
-	LastRestartedAt             *FleetTime             `json:"last_restarted_at"`
-	RefetchCriticalQueriesUntil *FleetTime             `json:"refetch_critical_queries_until"`
-	SeenTime                    FleetTime              `json:"seen_time"`
-	RefetchRequested            bool                   `json:"refetch_requested"`
-	Hostname                    string                 `json:"hostname"`
-	UUID                        string                 `json:"uuid"`
-	Platform                    string                 `json:"platform"`
-	OsqueryVersion              string                 `json:"osquery_version"`
-	OrbitVersion                *string                `json:"orbit_version"`
-	FleetDesktopVersion         *string                `json:"fleet_desktop_version"`
-	ScriptsEnabled              *bool                  `json:"scripts_enabled"`
-	OsVersion                   string                 `json:"os_version"`
-	Build                       string                 `json:"build"`
-	PlatformLike                string                 `json:"platform_like"`
-	CodeName                    string                 `json:"code_name"`
-	Uptime                      int64                  `json:"uptime"`
-	Memory                      int64                  `json:"memory"`
-	CPUType                     string                 `json:"cpu_type"`
-	CPUSubtype                  string                 `json:"cpu_subtype"`
-	CPUBrand                    string                 `json:"cpu_brand"`
-	CPUPhysicalCores            int                    `json:"cpu_physical_cores"`
-	CPULogicalCores             int                    `json:"cpu_logical_cores"`
-	HardwareVendor              string                 `json:"hardware_vendor"`
-	HardwareModel               string                 `json:"hardware_model"`
-	HardwareVersion             string                 `json:"hardware_version"`
-	HardwareSerial              string                 `json:"hardware_serial"`
-	ComputerName                string                 `json:"computer_name"`
-	DisplayName                 string                 `json:"display_name"`
-	PublicIP                    string                 `json:"public_ip"`
-	PrimaryIP                   string                 `json:"primary_ip"`
-	PrimaryMac                  string                 `json:"primary_mac"`
-	DistributedInterval         int                    `json:"distributed_interval"`
-	ConfigTLSRefresh            int                    `json:"config_tls_refresh"`
-	LoggerTLSPeriod             int                    `json:"logger_tls_period"`
-	TeamID                      *int                   `json:"team_id"`
-	TeamName                    *string                `json:"team_name"`
-	GigsDiskSpaceAvailable      float64                `json:"gigs_disk_space_available"`
-	PercentDiskSpaceAvailable   float64                `json:"percent_disk_space_available"`
-	GigsTotalDiskSpace          float64                `json:"gigs_total_disk_space"`
-	DiskEncryptionEnabled       *bool                  `json:"disk_encryption_enabled"`
-	Status                      string                 `json:"status"`
-	DisplayText                 string                 `json:"display_text"`
-	Additional                  *json.RawMessage       `json:"additional"`
-	Issues                      *HostIssues            `json:"issues"`
-	Batteries                   []HostBattery          `json:"batteries"`
-	Geolocation                 *HostGeolocation       `json:"geolocation"`
-	MaintenanceWindow           *HostMaintenanceWindow `json:"maintenance_window"`
-	Users                       []HostUser             `json:"users"`
-	EndUsers                    []HostEndUser          `json:"end_users"`
-	Labels                      []HostLabel            `json:"labels"`
-	Packs                       *json.RawMessage       `json:"packs"`
-	Policies                    []HostPolicy           `json:"policies"`
-	Software                    []HostSoftware         `json:"software"`
-	MDM                         *HostMDMDetail         `json:"mdm"`
-}
-
 // Custom transform to ensure MDM struct is marshalled to JSON string
 func mdmDetailToJSONString(ctx context.Context, d *transform.TransformData) (interface{}, error) {
 	if d.Value == nil {
 		return nil, nil
 	}
-	mdmData, ok := d.Value.(*HostMDMDetail)
+	mdmData, ok := d.Value.(*fleetapi.HostMDMDetail)
 	if !ok {
 		return nil, fmt.Errorf("mdmDetailToJSONString: type assertion to *HostMDMDetail failed for type %T", d.Value)
 	}
@@ -176,16 +37,32 @@ func mdmDetailToJSONString(ctx context.Context, d *transform.TransformData) (int
 
 func tableFleetdmHostDetail(ctx context.Context) *plugin.Table {
 	return &plugin.Table{
-		Name:        "fleetdm_host_detail",
-		Description: "Provides fully detailed information for each host by fetching details individually.",
+		Name:              "fleetdm_host_detail",
+		Description:       "Provides fully detailed information for each host by fetching details individually.",
+		GetMatrixItemFunc: serverMatrix,
 		List: &plugin.ListConfig{
 			Hydrate: listHostsForDetails,
+			Tags:    endpointTag(endpointFamilyHosts),
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "cache_bypass", Require: plugin.Optional}, // Skip the response cache
+				{Name: "server", Require: plugin.Optional},       // Query only this Fleet server
+			},
 		},
 		Get: &plugin.GetConfig{
-			KeyColumns: plugin.SingleColumn("id"),
-			Hydrate:    getHostDetails,
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "id", Require: plugin.Required},
+				{Name: "cache_bypass", Require: plugin.Optional},
+				{Name: "server", Require: plugin.Optional}, // Query only this Fleet server
+			},
+			Hydrate: getHostDetails,
+			// Also applies when getHostDetails hydrates columns of listed hosts
+			Tags: endpointTag(endpointFamilyHostDetail),
+			// A host id that does not exist, or was deleted mid-scan, yields no row
+			IgnoreConfig: &plugin.IgnoreConfig{
+				ShouldIgnoreErrorFunc: isNotFoundError,
+			},
 		},
-		Columns: []*plugin.Column{
+		Columns: withServerColumn([]*plugin.Column{
 			// Columns from the basic host list call (NO HYDRATE)
 			{Name: "id", Type: proto.ColumnType_INT, Description: "The unique ID of the host."},
 			{Name: "hostname", Type: proto.ColumnType_STRING, Description: "The hostname of the host."},
@@ -253,57 +130,42 @@ func tableFleetdmHostDetail(ctx context.Context) *plugin.Table {
 			{Name: "maintenance_window", Type: proto.ColumnType_JSON, Hydrate: getHostDetails, Transform: transform.FromField("MaintenanceWindow").Transform(arrayOrObjectToJSONString), Description: "Configured maintenance window for the host."},
 			{Name: "additional", Type: proto.ColumnType_JSON, Hydrate: getHostDetails, Transform: transform.FromField("Additional").Transform(arrayOrObjectToJSONString), Description: "Additional custom details for the host."},
 			{Name: "packs", Type: proto.ColumnType_JSON, Hydrate: getHostDetails, Transform: transform.FromField("Packs").Transform(arrayOrObjectToJSONString), Description: "Query packs applied to the host."},
-		},
+			{Name: "cache_bypass", Type: proto.ColumnType_BOOL, Transform: transform.FromQual("cache_bypass"), Description: "Set to true to skip the response cache configured with cache_dir and fetch fresh data. Set in WHERE clause."},
+		}),
 	}
 }
 
+// hostDetailPageSize is the default page listed for fleetdm_host_detail. Every
+// listed host is hydrated by getHostDetails, which is rate limited, so larger
+// pages gain nothing while their rows wait on hydrates.
+const hostDetailPageSize = 500
+
 // listHostsForDetails gets the minimal host object for hydration.
 func listHostsForDetails(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
-	client, err := NewFleetDMClient(ctx, d.Connection)
+	ctx = cacheBypassContext(ctx, d)
+	client, err := getClient(ctx, d)
 	if err != nil {
 		plugin.Logger(ctx).Error("fleetdm_host_detail.listHostsForDetails", "connection_error", err)
 		return nil, err
 	}
 
-	page := 0
-	perPage := 10000
-
-	for {
-		params := url.Values{}
-		params.Add("page", fmt.Sprintf("%d", page))
-		params.Add("per_page", fmt.Sprintf("%d", perPage))
-		params.Add("order_key", "id")
-		params.Add("order_direction", "asc")
-
-		var response ListHostsResponse
-		_, err := client.Get(ctx, "hosts", params, &response)
-		if err != nil {
-			plugin.Logger(ctx).Error("fleetdm_host_detail.listHostsForDetails", "api_error", err, "page", page)
-			return nil, err
-		}
-
-		for _, host := range response.Hosts {
-			d.StreamListItem(ctx, host)
-			if d.RowsRemaining(ctx) == 0 {
-				return nil, nil
-			}
-		}
-
-		if len(response.Hosts) < perPage {
-			break
-		}
-		page++
+	opts := fleetapi.ListHostsOptions{
+		ListOptions:    listOptions(ctx, d),
+		OrderDirection: "asc",
 	}
-
-	return nil, nil
+	if opts.PerPage == 0 {
+		opts.PerPage = hostDetailPageSize
+	}
+	return nil, client.ListHosts(tableContext(ctx, d), opts, streamItems[fleetapi.Host](ctx, d))
 }
 
 // getHostDetails is the hydrate function that fetches rich details for a single host.
 func getHostDetails(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	ctx = cacheBypassContext(ctx, d)
 	var hostID int
 	if h.Item != nil {
 		// h.Item is the minimal Host object from listHostsForDetails
-		hostFromList := h.Item.(Host) // Use the simpler Host struct here
+		hostFromList := h.Item.(fleetapi.Host) // Use the simpler Host struct here
 		hostID = hostFromList.ID
 	} else {
 		hostID = int(d.EqualsQuals["id"].GetInt64Value())
@@ -315,25 +177,17 @@ func getHostDetails(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateD
 
 	plugin.Logger(ctx).Info("fleetdm_host_detail.getHostDetails", "hydrating_host_id", hostID)
 
-	client, err := NewFleetDMClient(ctx, d.Connection)
+	client, err := getClient(ctx, d)
 	if err != nil {
 		plugin.Logger(ctx).Error("fleetdm_host_detail.getHostDetails", "connection_error", err, "host_id", hostID)
 		return nil, err
 	}
 
-	params := url.Values{}
-	addHostPopulationParams(params)
-
-	var response struct {
-		Host HostDetail `json:"host"` // Use the new rich HostDetail struct
-	}
-	endpointPath := fmt.Sprintf("hosts/%d", hostID)
-	_, err = client.Get(ctx, endpointPath, params, &response)
-
+	host, err := client.GetHost(tableContext(ctx, d), uint(hostID), fleetapi.GetHostOptions{Populate: hostPopulateParams(d)})
 	if err != nil {
 		plugin.Logger(ctx).Error("fleetdm_host_detail.getHostDetails", "client_get_error", err, "host_id", hostID)
 		return nil, err
 	}
 
-	return response.Host, nil
+	return *host, nil
 }
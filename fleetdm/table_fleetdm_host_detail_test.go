@@ -0,0 +1,97 @@
+package fleetdm_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListHostDetails(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "page_size = 2")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_detail",
+		Columns: []string{"id", "hostname", "software", "batteries"},
+	})
+
+	assertColumn(t, rows, "id", 1, 2, 3, 4, 5)
+	assertColumn(t, rows, "hostname", "alice-mbp.local", "bob-thinkpad", "web-01.prod", "web-02.prod", "kiosk-lobby")
+	assertRequests(t, fake, "hosts", 3)
+	for id := 1; id <= 5; id++ {
+		assertRequests(t, fake, fmt.Sprintf("hosts/%d", id), 1)
+	}
+	for _, r := range rows {
+		if r["id"] != int64(1) {
+			continue
+		}
+		if software, _ := r["software"].([]any); len(software) != 1 {
+			t.Errorf("software = %v", r["software"])
+		}
+		if batteries, _ := r["batteries"].([]any); len(batteries) != 1 {
+			t.Errorf("batteries = %v", r["batteries"])
+		}
+	}
+}
+
+func TestGetHostDetails(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_detail",
+		Columns: []string{"id", "hostname", "hardware_serial", "issues", "labels", "last_restarted_at"},
+		Quals:   map[string]any{"id": 2},
+	})
+
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	r := rows[0]
+	if r["hostname"] != "bob-thinkpad" || r["hardware_serial"] != "PF3BOB02" || r["last_restarted_at"] != "2024-05-18T08:00:00Z" {
+		t.Errorf("unexpected host 2: %v", r)
+	}
+	if issues, _ := r["issues"].(map[string]any); issues["total_issues_count"] != float64(3) {
+		t.Errorf("issues = %v", r["issues"])
+	}
+	if labels, _ := r["labels"].([]any); len(labels) != 1 {
+		t.Errorf("labels = %v", r["labels"])
+	}
+	assertRequests(t, fake, "hosts", 0)
+	params := fake.Requests("hosts/2")[0]
+	// Only the selected labels column is populated
+	if params.Get("populate_labels") != "true" || params.Has("populate_policies") || params.Has("populate_users") {
+		t.Errorf("unexpected population params: %v", params)
+	}
+}
+
+func TestGetHostDetailsNotFound(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_detail",
+		Columns: []string{"id", "hostname"},
+		Quals:   map[string]any{"id": 999},
+	})
+
+	if len(rows) != 0 {
+		t.Errorf("got %d rows for a missing host, want 0", len(rows))
+	}
+	assertRequests(t, fake, "hosts/999", 1)
+}
+
+func TestGetHostDetailsError(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	fake.InjectError("hosts/2", 403, 0)
+	conn := newTestConnection(t, fake, "")
+
+	_, err := conn.execute(testQuery{
+		Table:   "fleetdm_host_detail",
+		Columns: []string{"id", "hostname"},
+		Quals:   map[string]any{"id": 2},
+	})
+
+	assertErrorContains(t, err, "403 Forbidden")
+}
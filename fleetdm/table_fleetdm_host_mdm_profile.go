@@ -0,0 +1,124 @@
+package fleetdm
+
+import (
+	"context"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleetapi"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// HostMDMProfileRow is the status of one configuration profile on one host.
+type HostMDMProfileRow struct {
+	fleetapi.HostMDMProfile
+	HostID   int
+	Hostname string
+	// Status is the profile's status, with the null status of pending profiles
+	// reported as "pending"
+	Status string
+}
+
+func tableFleetdmHostMDMProfile(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:              "fleetdm_host_mdm_profile",
+		Description:       "Status of MDM configuration profiles on FleetDM hosts, with one row per host and profile. Uses the mdm.profiles of the /hosts/:id endpoint.",
+		GetMatrixItemFunc: serverMatrix,
+		List: &plugin.ListConfig{
+			Hydrate: listHostMDMProfiles,
+			Tags:    endpointTag(endpointFamilyHostDetail),
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "host_id", Require: plugin.Optional},      // Fetch only this host's profiles
+				{Name: "profile_uuid", Require: plugin.Optional}, // With status, maps to API 'profile_uuid' host filter
+				{Name: "status", Require: plugin.Optional},       // With profile_uuid, maps to API 'profile_status' host filter
+				{Name: "cache_bypass", Require: plugin.Optional}, // Skip the response cache
+				{Name: "server", Require: plugin.Optional},       // Query only this Fleet server
+			},
+		},
+		Columns: withServerColumn([]*plugin.Column{
+			{Name: "host_id", Type: proto.ColumnType_INT, Description: "ID of the host."},
+			{Name: "hostname", Type: proto.ColumnType_STRING, Description: "Hostname of the host."},
+			{Name: "profile_uuid", Type: proto.ColumnType_STRING, Transform: transform.FromField("ProfileUUID"), Description: "UUID of the configuration profile."},
+			{Name: "name", Type: proto.ColumnType_STRING, Description: "Name of the configuration profile."},
+			{Name: "operation_type", Type: proto.ColumnType_STRING, Description: "Operation Fleet performs on the host: 'install' or 'remove'."},
+			{Name: "status", Type: proto.ColumnType_STRING, Description: "Status of the operation: 'verified', 'verifying', 'pending' or 'failed'."},
+			{Name: "detail", Type: proto.ColumnType_STRING, Description: "Details reported by the host, such as the error of a failed profile."},
+			{Name: "platform", Type: proto.ColumnType_STRING, Description: "Platform of the configuration profile (e.g., 'darwin', 'windows')."},
+			{Name: "cache_bypass", Type: proto.ColumnType_BOOL, Transform: transform.FromQual("cache_bypass"), Description: "Set to true to skip the response cache configured with cache_dir and fetch fresh data. Set in WHERE clause."},
+		}),
+	}
+}
+
+func listHostMDMProfiles(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	ctx = cacheBypassContext(ctx, d)
+	client, err := getClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("fleetdm_host_mdm_profile.listHostMDMProfiles", "connection_error", err)
+		return nil, err
+	}
+
+	profileUUID := qualString(d, "profile_uuid")
+	status := qualString(d, "status")
+
+	var hostIDs []uint
+	if hostID := qualUint(d, "host_id"); hostID != nil {
+		hostIDs = append(hostIDs, *hostID)
+	} else {
+		opts := fleetapi.ListHostsOptions{ListOptions: listOptions(ctx, d), OrderDirection: "asc"}
+		// Rows are host-profile pairs, not hosts, so the query's limit is applied to
+		// the rows as they are streamed
+		opts.Limit = 0
+		if profileUUID != "" && status != "" {
+			// Fleet only filters hosts by profile when both are set
+			opts.ProfileUUID = profileUUID
+			opts.ProfileStatus = status
+		}
+		err := client.ListHosts(tableContext(ctx, d), opts, func(host fleetapi.Host) bool {
+			// Hosts which are not enrolled in MDM have no profiles
+			if host.MDM != nil && host.MDM.EnrollmentStatus != "" && host.MDM.EnrollmentStatus != "Off" {
+				hostIDs = append(hostIDs, uint(host.ID))
+			}
+			return true
+		})
+		if err != nil {
+			plugin.Logger(ctx).Error("fleetdm_host_mdm_profile.listHostMDMProfiles", "hosts_api_error", err)
+			return nil, err
+		}
+	}
+
+	for _, hostID := range hostIDs {
+		d.WaitForListRateLimit(ctx)
+		host, err := client.GetHost(tableContext(ctx, d), hostID, fleetapi.GetHostOptions{})
+		if err != nil {
+			if isNotFoundError(ctx, d, h, err) {
+				// The host was deleted after the hosts were listed
+				plugin.Logger(ctx).Warn("fleetdm_host_mdm_profile.listHostMDMProfiles", "host_not_found", hostID)
+				continue
+			}
+			plugin.Logger(ctx).Error("fleetdm_host_mdm_profile.listHostMDMProfiles", "api_error", err, "host_id", hostID)
+			return nil, err
+		}
+		profiles, err := host.MDM.MDMProfiles()
+		if err != nil {
+			plugin.Logger(ctx).Error("fleetdm_host_mdm_profile.listHostMDMProfiles", "decode_error", err, "host_id", hostID)
+			return nil, err
+		}
+
+		for _, profile := range profiles {
+			row := HostMDMProfileRow{HostMDMProfile: profile, HostID: host.ID, Hostname: host.Hostname, Status: "pending"}
+			if profile.Status != nil {
+				row.Status = *profile.Status
+			}
+			if (profileUUID != "" && row.ProfileUUID != profileUUID) || (status != "" && row.Status != status) {
+				continue
+			}
+			d.StreamListItem(ctx, row)
+			if d.RowsRemaining(ctx) == 0 {
+				plugin.Logger(ctx).Debug("fleetdm_host_mdm_profile.listHostMDMProfiles", "limit_reached", true)
+				return nil, nil
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,109 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+const wifiProfileUUID = "a6f1c2e0-1b7d-4c55-9e0f-1d2c3b4a5f02"
+
+func TestListHostMDMProfiles(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_mdm_profile",
+		Columns: []string{"host_id", "hostname", "profile_uuid", "name", "operation_type", "status", "detail", "platform"},
+	})
+
+	assertColumn(t, rows, "host_id", 1, 1, 1, 2, 2)
+	assertColumn(t, rows, "status", "verified", "failed", "pending", "verifying", "pending")
+	assertRequests(t, fake, "hosts", 1)
+	// Hosts 3 to 5 are not enrolled in MDM
+	assertRequests(t, fake, "hosts/1", 1)
+	assertRequests(t, fake, "hosts/2", 1)
+	assertRequests(t, fake, "hosts/3", 0)
+	for _, r := range rows {
+		switch r["profile_uuid"] {
+		case wifiProfileUUID:
+			if r["hostname"] != "alice-mbp.local" || r["name"] != "Corporate Wi-Fi" || r["operation_type"] != "install" || r["platform"] != "darwin" {
+				t.Errorf("unexpected Wi-Fi row: %v", r)
+			}
+			if r["detail"] != "The profile could not be installed: the Wi-Fi payload is invalid." {
+				t.Errorf("detail = %v", r["detail"])
+			}
+		case "w2d9b7a4-6e3f-4a1c-8b2d-5f6e7a8b9c02":
+			if r["operation_type"] != "remove" || r["platform"] != "windows" {
+				t.Errorf("unexpected Legacy VPN row: %v", r)
+			}
+		}
+	}
+}
+
+func TestListHostMDMProfilesByProfileAndStatus(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_mdm_profile",
+		Columns: []string{"host_id", "profile_uuid", "status"},
+		Quals:   map[string]any{"profile_uuid": wifiProfileUUID, "status": "failed"},
+	})
+
+	assertColumn(t, rows, "host_id", 1)
+	assertColumn(t, rows, "profile_uuid", wifiProfileUUID)
+	params := fake.Requests("hosts")[0]
+	if params.Get("profile_uuid") != wifiProfileUUID || params.Get("profile_status") != "failed" {
+		t.Errorf("request params = %v", params)
+	}
+	// Only the matching host is fetched
+	assertRequests(t, fake, "hosts/2", 0)
+}
+
+func TestListHostMDMProfilesByStatus(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_mdm_profile",
+		Columns: []string{"host_id", "name", "status"},
+		Quals:   map[string]any{"status": "pending"},
+	})
+
+	// Profiles without a status are pending
+	assertColumn(t, rows, "name", "Passcode policy", "Legacy VPN")
+	if params := fake.Requests("hosts")[0]; params.Has("profile_status") {
+		t.Errorf("profile_status requires profile_uuid and should not be sent: %v", params)
+	}
+}
+
+func TestListHostMDMProfilesByHost(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_mdm_profile",
+		Columns: []string{"host_id", "name"},
+		Quals:   map[string]any{"host_id": 2},
+	})
+
+	assertColumn(t, rows, "name", "Windows Defender settings", "Legacy VPN")
+	assertRequests(t, fake, "hosts", 0)
+	assertRequests(t, fake, "hosts/2", 1)
+}
+
+func TestListHostMDMProfilesUnenrolledHost(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_mdm_profile",
+		Columns: []string{"host_id"},
+		Quals:   map[string]any{"host_id": 3},
+	})
+
+	if len(rows) != 0 {
+		t.Errorf("got %d rows, want none", len(rows))
+	}
+}
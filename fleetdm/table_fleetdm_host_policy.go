@@ -0,0 +1,129 @@
+package fleetdm
+
+import (
+	"context"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleetapi"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// HostPolicyRow is the response of one host to one policy.
+type HostPolicyRow struct {
+	fleetapi.HostPolicy
+	HostID   int
+	Hostname string
+	TeamID   *int
+	TeamName *string
+}
+
+// policyResponseFilters maps the response column to Fleet's policy_response host filter.
+var policyResponseFilters = map[string]string{
+	"pass": "passing",
+	"fail": "failing",
+}
+
+func tableFleetdmHostPolicy(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:              "fleetdm_host_policy",
+		Description:       "Policy compliance results in FleetDM, with one row per host and policy.",
+		GetMatrixItemFunc: serverMatrix,
+		List: &plugin.ListConfig{
+			Hydrate: listHostPolicies,
+			Tags:    endpointTag(endpointFamilyHosts),
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "host_id", Require: plugin.Optional},      // Fetch only this host's policies
+				{Name: "policy_id", Require: plugin.Optional},    // Maps to API 'policy_id' host filter
+				{Name: "response", Require: plugin.Optional},     // Maps to API 'policy_response' host filter, requires policy_id
+				{Name: "team_id", Require: plugin.Optional},      // Maps to API 'team_id' host filter
+				{Name: "cache_bypass", Require: plugin.Optional}, // Skip the response cache
+				{Name: "server", Require: plugin.Optional},       // Query only this Fleet server
+			},
+		},
+		Columns: withServerColumn([]*plugin.Column{
+			{Name: "host_id", Type: proto.ColumnType_INT, Description: "ID of the host."},
+			{Name: "hostname", Type: proto.ColumnType_STRING, Description: "Hostname of the host."},
+			{Name: "team_id", Type: proto.ColumnType_INT, Description: "ID of the team the host belongs to. Null for hosts without a team."},
+			{Name: "team_name", Type: proto.ColumnType_STRING, Description: "Name of the team the host belongs to."},
+			{Name: "policy_id", Type: proto.ColumnType_INT, Transform: transform.FromField("HostPolicy.ID"), Description: "ID of the policy."},
+			{Name: "policy_name", Type: proto.ColumnType_STRING, Transform: transform.FromField("HostPolicy.Name"), Description: "Name of the policy."},
+			{Name: "policy_team_id", Type: proto.ColumnType_INT, Transform: transform.FromField("HostPolicy.TeamID"), Description: "ID of the team the policy belongs to. Null for global policies."},
+			{Name: "critical", Type: proto.ColumnType_BOOL, Transform: transform.FromField("Critical"), Description: "True if the policy is marked critical (Fleet Premium)."},
+			{Name: "response", Type: proto.ColumnType_STRING, Description: "Response of the host to the policy: 'pass', 'fail', or null if the host has not run the policy yet."},
+			{Name: "platform", Type: proto.ColumnType_STRING, Description: "Comma-separated platforms the policy targets. Empty targets all platforms."},
+			{Name: "resolution", Type: proto.ColumnType_STRING, Description: "Steps to resolve a failing policy."},
+			{Name: "query", Type: proto.ColumnType_STRING, Description: "SQL query of the policy."},
+			{Name: "cache_bypass", Type: proto.ColumnType_BOOL, Transform: transform.FromQual("cache_bypass"), Description: "Set to true to skip the response cache configured with cache_dir and fetch fresh data. Set in WHERE clause."},
+		}),
+	}
+}
+
+func listHostPolicies(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	ctx = cacheBypassContext(ctx, d)
+	client, err := getClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("fleetdm_host_policy.listHostPolicies", "connection_error", err)
+		return nil, err
+	}
+
+	policyID := qualUint(d, "policy_id")
+	limitReached := false
+	// streamHost streams a row for each of the host's policies, or only for
+	// policy_id when it is set. It returns false once the query's limit is reached.
+	streamHost := func(host HostPolicyRow, policies []fleetapi.HostPolicy) bool {
+		for _, policy := range policies {
+			if policyID != nil && policy.ID != *policyID {
+				continue
+			}
+			host.HostPolicy = policy
+			d.StreamListItem(ctx, host)
+			if d.RowsRemaining(ctx) == 0 {
+				limitReached = true
+				return false
+			}
+		}
+		return true
+	}
+
+	if hostID := qualUint(d, "host_id"); hostID != nil {
+		host, err := client.GetHost(tableContext(ctx, d), *hostID, fleetapi.GetHostOptions{})
+		if err != nil {
+			if isNotFoundError(ctx, d, h, err) {
+				return nil, nil
+			}
+			plugin.Logger(ctx).Error("fleetdm_host_policy.listHostPolicies", "api_error", err, "host_id", *hostID)
+			return nil, err
+		}
+		streamHost(HostPolicyRow{HostID: host.ID, Hostname: host.Hostname, TeamID: host.TeamID, TeamName: host.TeamName}, host.Policies)
+		return nil, nil
+	}
+
+	opts := fleetapi.ListHostsOptions{
+		ListOptions:    listOptions(ctx, d),
+		OrderDirection: "asc",
+		TeamID:         qualUint(d, "team_id"),
+		Populate:       []string{"populate_policies"},
+	}
+	// Rows are host-policy pairs, not hosts, so the query's limit is applied to
+	// the rows as they are streamed
+	opts.Limit = 0
+	if policyID != nil {
+		// Only hosts which responded to the policy are listed, instead of every host
+		opts.PolicyID = policyID
+		opts.PolicyResponse = policyResponseFilters[qualString(d, "response")]
+	}
+
+	err = client.ListHosts(tableContext(ctx, d), opts, func(host fleetapi.Host) bool {
+		return streamHost(HostPolicyRow{HostID: host.ID, Hostname: host.Hostname, TeamID: host.TeamID, TeamName: host.TeamName}, host.Policies)
+	})
+	if err != nil {
+		plugin.Logger(ctx).Error("fleetdm_host_policy.listHostPolicies", "api_error", err)
+		return nil, err
+	}
+	if limitReached {
+		plugin.Logger(ctx).Debug("fleetdm_host_policy.listHostPolicies", "limit_reached", true)
+	}
+	return nil, nil
+}
@@ -0,0 +1,111 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListHostPolicies(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_policy",
+		Columns: []string{"host_id", "hostname", "team_id", "policy_id", "policy_name", "policy_team_id", "critical", "response"},
+	})
+
+	assertColumn(t, rows, "host_id", 1, 1, 2)
+	assertColumn(t, rows, "policy_id", 1, 2, 3)
+	assertRequests(t, fake, "hosts", 1)
+	if params := fake.Requests("hosts")[0]; params.Get("populate_policies") != "true" || params.Has("policy_id") {
+		t.Errorf("request params = %v", params)
+	}
+	for _, r := range rows {
+		switch r["policy_id"] {
+		case int64(1):
+			if r["hostname"] != "alice-mbp.local" || r["policy_name"] != "Disk encryption enabled" || r["critical"] != true || r["response"] != "pass" {
+				t.Errorf("unexpected row for policy 1: %v", r)
+			}
+			if r["team_id"] != int64(1) || r["policy_team_id"] != nil {
+				t.Errorf("a global policy should have no policy_team_id and the host's team_id: %v", r)
+			}
+		case int64(2):
+			if r["critical"] != false || r["response"] != "fail" {
+				t.Errorf("unexpected row for policy 2: %v", r)
+			}
+		case int64(3):
+			if r["hostname"] != "bob-thinkpad" || r["policy_team_id"] != int64(1) {
+				t.Errorf("unexpected row for policy 3: %v", r)
+			}
+		}
+	}
+}
+
+func TestListHostPoliciesByPolicy(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_policy",
+		Columns: []string{"host_id", "policy_id", "response"},
+		Quals:   map[string]any{"policy_id": 2, "response": "fail"},
+	})
+
+	// Host 1 also responded to policy 1, which must not be returned
+	assertColumn(t, rows, "host_id", 1)
+	assertColumn(t, rows, "policy_id", 2)
+	params := fake.Requests("hosts")[0]
+	if params.Get("policy_id") != "2" || params.Get("policy_response") != "failing" {
+		t.Errorf("request params = %v", params)
+	}
+}
+
+func TestListHostPoliciesByPolicyPassing(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_policy",
+		Columns: []string{"host_id"},
+		Quals:   map[string]any{"policy_id": 2, "response": "pass"},
+	})
+
+	if len(rows) != 0 {
+		t.Errorf("got %d rows, want none: %v", len(rows), rows)
+	}
+	if params := fake.Requests("hosts")[0]; params.Get("policy_response") != "passing" {
+		t.Errorf("request params = %v", params)
+	}
+}
+
+func TestListHostPoliciesByHost(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_policy",
+		Columns: []string{"host_id", "hostname", "policy_id"},
+		Quals:   map[string]any{"host_id": 2},
+	})
+
+	assertColumn(t, rows, "policy_id", 3)
+	assertColumn(t, rows, "hostname", "bob-thinkpad")
+	assertRequests(t, fake, "hosts/2", 1)
+	assertRequests(t, fake, "hosts", 0)
+}
+
+func TestListHostPoliciesUnknownHost(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_policy",
+		Columns: []string{"host_id"},
+		Quals:   map[string]any{"host_id": 999},
+	})
+
+	if len(rows) != 0 {
+		t.Errorf("got %d rows, want none", len(rows))
+	}
+}
@@ -0,0 +1,144 @@
+package fleetdm
+
+import (
+	"context"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleetapi"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// HostSoftwareRow is one installed version of a software title on a host.
+type HostSoftwareRow struct {
+	fleetapi.HostSoftwareInstalledVersion
+	HostID               uint
+	SoftwareTitleID      uint
+	Name                 string
+	Status               *string
+	VulnerabilitiesCount int
+	Vulnerable           bool
+}
+
+func tableFleetdmHostSoftware(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:              "fleetdm_host_software",
+		Description:       "Software installed on FleetDM hosts, with one row per host and installed software version. Uses the /hosts/:id/software endpoint.",
+		GetMatrixItemFunc: serverMatrix,
+		List: &plugin.ListConfig{
+			Hydrate: listHostSoftware,
+			Tags:    endpointTag(endpointFamilyHostDetail),
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "host_id", Require: plugin.Optional},      // Without it, every host is queried
+				{Name: "vulnerable", Require: plugin.Optional},   // Maps to API 'vulnerable' param
+				{Name: "query", Require: plugin.Optional},        // Search by software name
+				{Name: "cache_bypass", Require: plugin.Optional}, // Skip the response cache
+				{Name: "server", Require: plugin.Optional},       // Query only this Fleet server
+			},
+		},
+		Columns: withServerColumn([]*plugin.Column{
+			{Name: "host_id", Type: proto.ColumnType_INT, Description: "ID of the host the software is installed on."},
+			{Name: "software_title_id", Type: proto.ColumnType_INT, Description: "ID of the software title."},
+			{Name: "software_id", Type: proto.ColumnType_INT, Transform: transform.FromField("SoftwareID"), Description: "ID of the installed software version."},
+			{Name: "name", Type: proto.ColumnType_STRING, Description: "Name of the software (e.g., 'Google Chrome.app')."},
+			{Name: "version", Type: proto.ColumnType_STRING, Description: "Installed version of the software."},
+			{Name: "source", Type: proto.ColumnType_STRING, Description: "Source of the software inventory (e.g., 'apps', 'programs', 'deb_packages')."},
+			{Name: "status", Type: proto.ColumnType_STRING, Description: "Install status when the software is a package managed by Fleet (e.g., 'installed', 'failed_install')."},
+			{Name: "bundle_identifier", Type: proto.ColumnType_STRING, Description: "Bundle identifier for macOS applications."},
+			{Name: "last_opened_at", Type: proto.ColumnType_TIMESTAMP, Transform: transform.FromField("LastOpenedAt").Transform(flexibleTimeTransform), Description: "Timestamp when the software was last opened on the host, where the source reports it."},
+			{Name: "installed_paths", Type: proto.ColumnType_JSON, Description: "Paths the software version is installed at on the host."},
+			{Name: "generated_cpe", Type: proto.ColumnType_STRING, Transform: transform.FromField("GeneratedCPE"), Description: "Generated Common Platform Enumeration (CPE) string, if the server reports it."},
+			{Name: "vulnerabilities", Type: proto.ColumnType_JSON, Description: "CVE identifiers of the known vulnerabilities of the installed version."},
+			{Name: "vulnerabilities_count", Type: proto.ColumnType_INT, Transform: transform.FromField("VulnerabilitiesCount"), Description: "Number of known vulnerabilities of the installed version."},
+			{Name: "vulnerable", Type: proto.ColumnType_BOOL, Transform: transform.FromField("Vulnerable"), Description: "True if the installed version has known vulnerabilities. Set in WHERE clause to only fetch vulnerable software."},
+
+			// Query parameters that can be used for filtering (key columns)
+			{Name: "query", Type: proto.ColumnType_STRING, Transform: transform.FromQual("query"), Description: "Search query keywords. Searchable fields include the software name. Set in WHERE clause."},
+			{Name: "cache_bypass", Type: proto.ColumnType_BOOL, Transform: transform.FromQual("cache_bypass"), Description: "Set to true to skip the response cache configured with cache_dir and fetch fresh data. Set in WHERE clause."},
+		}),
+	}
+}
+
+func listHostSoftware(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	ctx = cacheBypassContext(ctx, d)
+	client, err := getClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("fleetdm_host_software.listHostSoftware", "connection_error", err)
+		return nil, err
+	}
+
+	var hostIDs []uint
+	if hostID := qualUint(d, "host_id"); hostID != nil {
+		hostIDs = append(hostIDs, *hostID)
+	} else {
+		plugin.Logger(ctx).Info("fleetdm_host_software.listHostSoftware", "discovering_all_hosts", true)
+		hosts, err := collectHosts(ctx, d, client)
+		if err != nil {
+			plugin.Logger(ctx).Error("fleetdm_host_software.listHostSoftware", "hosts_api_error", err)
+			return nil, err
+		}
+		for _, host := range hosts {
+			hostIDs = append(hostIDs, uint(host.ID))
+		}
+	}
+
+	opts := fleetapi.ListHostSoftwareOptions{
+		ListOptions: listOptions(ctx, d),
+		Query:       qualString(d, "query"),
+	}
+	// Rows are installed versions, not titles, so the query's limit is applied to
+	// the rows as they are streamed
+	opts.Limit = 0
+	// Fleet has no filter for titles without vulnerabilities, and filters
+	// vulnerable titles rather than versions, so the qual is also checked on
+	// each row below
+	vulnerable := qualBool(d, "vulnerable")
+	if vulnerable != nil && *vulnerable {
+		opts.Vulnerable = vulnerable
+	}
+
+	for _, hostID := range hostIDs {
+		limitReached := false
+		err := client.ListHostSoftware(tableContext(ctx, d), hostID, opts, func(title fleetapi.HostSoftwareTitle) bool {
+			// Software only available for install on the host has no installed versions
+			for _, installed := range title.InstalledVersions {
+				row := HostSoftwareRow{
+					HostSoftwareInstalledVersion: installed,
+					HostID:                       hostID,
+					SoftwareTitleID:              title.ID,
+					Name:                         title.Name,
+					Status:                       title.Status,
+					VulnerabilitiesCount:         len(installed.Vulnerabilities),
+					Vulnerable:                   len(installed.Vulnerabilities) > 0,
+				}
+				if vulnerable != nil && row.Vulnerable != *vulnerable {
+					continue
+				}
+				if row.Source == "" {
+					row.Source = title.Source
+				}
+				d.StreamListItem(ctx, row)
+				if d.RowsRemaining(ctx) == 0 {
+					limitReached = true
+					return false
+				}
+			}
+			return true
+		})
+		if err != nil {
+			if isNotFoundError(ctx, d, h, err) {
+				// The host was deleted after the hosts were listed
+				plugin.Logger(ctx).Warn("fleetdm_host_software.listHostSoftware", "host_not_found", hostID)
+				continue
+			}
+			plugin.Logger(ctx).Error("fleetdm_host_software.listHostSoftware", "api_error", err, "host_id", hostID)
+			return nil, err
+		}
+		if limitReached {
+			plugin.Logger(ctx).Debug("fleetdm_host_software.listHostSoftware", "limit_reached", true)
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,120 @@
+package fleetdm_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListHostSoftware(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_software",
+		Columns: []string{"host_id", "software_title_id", "name", "version", "source", "status", "bundle_identifier", "last_opened_at", "installed_paths", "generated_cpe", "vulnerabilities_count", "vulnerable"},
+		Quals:   map[string]any{"host_id": 1},
+	})
+
+	// Python has two installed versions; Slack is pending install and has none
+	assertColumn(t, rows, "version", "125.0.6422.142", "3.11.9", "3.12.3")
+	assertColumn(t, rows, "host_id", 1, 1, 1)
+	assertRequests(t, fake, "hosts/1/software", 1)
+	assertRequests(t, fake, "hosts", 0)
+	for _, r := range rows {
+		switch r["version"] {
+		case "125.0.6422.142":
+			if r["name"] != "Google Chrome.app" || r["software_title_id"] != int64(11) || r["source"] != "apps" || r["status"] != "installed" {
+				t.Errorf("unexpected Chrome row: %v", r)
+			}
+			if r["bundle_identifier"] != "com.google.Chrome" || r["last_opened_at"] != "2024-06-01T09:30:00Z" {
+				t.Errorf("unexpected bundle identifier or last opened time: %v", r)
+			}
+			if r["generated_cpe"] != "cpe:2.3:a:google:chrome:125.0.6422.142:*:*:*:*:macos:*:*" {
+				t.Errorf("generated_cpe = %v", r["generated_cpe"])
+			}
+			if r["vulnerabilities_count"] != int64(1) || r["vulnerable"] != true {
+				t.Errorf("unexpected vulnerabilities: %v", r)
+			}
+			if paths, _ := r["installed_paths"].([]any); len(paths) != 1 || paths[0] != "/Applications/Google Chrome.app" {
+				t.Errorf("installed_paths = %v", r["installed_paths"])
+			}
+		case "3.11.9":
+			if r["name"] != "Python.app" || r["status"] != nil || r["last_opened_at"] != nil {
+				t.Errorf("unexpected Python row: %v", r)
+			}
+			if r["vulnerabilities_count"] != int64(0) || r["vulnerable"] != false {
+				t.Errorf("a version without vulnerabilities should count 0 and not be vulnerable: %v", r)
+			}
+		}
+	}
+}
+
+func TestListHostSoftwareAllHosts(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_software",
+		Columns: []string{"host_id", "name"},
+	})
+
+	assertColumn(t, rows, "host_id", 1, 1, 1, 3, 4)
+	assertRequests(t, fake, "hosts", 1)
+	for id := 1; id <= 5; id++ {
+		assertRequests(t, fake, fmt.Sprintf("hosts/%d/software", id), 1)
+	}
+}
+
+func TestListHostSoftwareFilters(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_software",
+		Columns: []string{"name", "version"},
+		Quals:   map[string]any{"host_id": 1, "vulnerable": true, "query": "chrome"},
+	})
+
+	assertColumn(t, rows, "name", "Google Chrome.app")
+	params := fake.Requests("hosts/1/software")[0]
+	if params.Get("vulnerable") != "true" || params.Get("query") != "chrome" {
+		t.Errorf("request params = %v", params)
+	}
+}
+
+func TestListHostSoftwareNotVulnerable(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_software",
+		Columns: []string{"name", "version"},
+		Quals:   map[string]any{"host_id": 1, "vulnerable": false},
+	})
+
+	// Fleet cannot filter for software without vulnerabilities, so the rows are
+	// filtered as they are streamed
+	assertColumn(t, rows, "version", "3.11.9", "3.12.3")
+	if params := fake.Requests("hosts/1/software")[0]; params.Has("vulnerable") {
+		t.Errorf("vulnerable = false should not be sent: %v", params)
+	}
+}
+
+func TestListHostSoftwareLimit(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host_software",
+		Columns: []string{"host_id", "version"},
+		Limit:   2,
+	})
+
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	assertRequests(t, fake, "hosts/1/software", 1)
+	assertRequests(t, fake, "hosts/3/software", 0)
+}
@@ -0,0 +1,199 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListHosts(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host",
+		Columns: []string{"id", "hostname", "platform", "status", "team_id", "team_name", "memory", "gigs_disk_space_available", "seen_time", "orbit_version", "mdm", "policies", "device_mapping"},
+	})
+
+	assertColumn(t, rows, "id", 5, 4, 3, 2, 1)
+	assertRequests(t, fake, "hosts", 1)
+	for _, r := range rows {
+		if r["id"] != int64(1) {
+			continue
+		}
+		if r["hostname"] != "alice-mbp.local" || r["platform"] != "darwin" || r["status"] != "online" {
+			t.Errorf("unexpected host 1: %v", r)
+		}
+		if r["team_id"] != int64(1) || r["team_name"] != "Workstations" || r["memory"] != int64(34359738368) {
+			t.Errorf("unexpected team or memory for host 1: %v", r)
+		}
+		if r["gigs_disk_space_available"] != 412.5 || r["seen_time"] != "2024-06-01T12:00:00Z" || r["orbit_version"] != "1.28.0" {
+			t.Errorf("unexpected disk, seen time or orbit version for host 1: %v", r)
+		}
+		if mdm, _ := r["mdm"].(map[string]any); mdm["enrollment_status"] != "On (automatic)" {
+			t.Errorf("mdm = %v", r["mdm"])
+		}
+		if policies, _ := r["policies"].([]any); len(policies) != 2 {
+			t.Errorf("policies = %v", r["policies"])
+		}
+		if mapping, _ := r["device_mapping"].([]any); len(mapping) != 1 {
+			t.Errorf("device_mapping = %v", r["device_mapping"])
+		}
+	}
+}
+
+func TestListHostsPaginates(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "page_size = 2")
+
+	rows := conn.rows(testQuery{Table: "fleetdm_host", Columns: []string{"id"}})
+
+	assertColumn(t, rows, "id", 5, 4, 3, 2, 1)
+	requests := fake.Requests("hosts")
+	if len(requests) != 3 {
+		t.Fatalf("requests to hosts = %d, want 3: %v", len(requests), requests)
+	}
+	for i, params := range requests {
+		if params.Get("page") != []string{"0", "1", "2"}[i] || params.Get("per_page") != "2" {
+			t.Errorf("request %d params = %v", i, params)
+		}
+		if params.Get("order_key") != "id" || params.Get("order_direction") != "desc" {
+			t.Errorf("request %d is not ordered by id: %v", i, params)
+		}
+	}
+}
+
+func TestListHostsStopsAtLimit(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{Table: "fleetdm_host", Columns: []string{"id"}, Limit: 2})
+
+	assertColumn(t, rows, "id", 5, 4)
+	requests := fake.Requests("hosts")
+	if len(requests) != 1 || requests[0].Get("per_page") != "2" {
+		t.Errorf("expected a single request for 2 hosts, got %v", requests)
+	}
+}
+
+func TestListHostsQuals(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_host",
+		Columns: []string{"id", "team_id", "status"},
+		Quals:   map[string]any{"team_id": 2, "status": "online"},
+	})
+
+	assertColumn(t, rows, "id", 4, 3)
+	params := fake.Requests("hosts")[0]
+	if params.Get("team_id") != "2" || params.Get("status") != "online" {
+		t.Errorf("quals not sent as params: %v", params)
+	}
+}
+
+func TestListHostsPremiumQualOnFreeTier(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{Tier: fleettest.TierFree})
+	conn := newTestConnection(t, fake, "")
+
+	_, err := conn.execute(testQuery{
+		Table:   "fleetdm_host",
+		Columns: []string{"id"},
+		Quals:   map[string]any{"low_disk_space": 10},
+	})
+
+	assertErrorContains(t, err, "the low_disk_space qual requires Fleet Premium")
+	assertRequests(t, fake, "hosts", 0)
+}
+
+func TestListHostsPremiumQualWithoutDetection(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{Tier: fleettest.TierFree})
+	fake.InjectError("config", 500, 0)
+	conn := newTestConnection(t, fake, "")
+
+	// Without the license tier the query reaches the API, which rejects it
+	_, err := conn.execute(testQuery{
+		Table:   "fleetdm_host",
+		Columns: []string{"id"},
+		Quals:   map[string]any{"low_disk_space": 10},
+	})
+
+	assertErrorContains(t, err, "402 Payment Required: Requires Fleet Premium license")
+}
+
+func TestListHostsAPIError(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	fake.InjectError("hosts", 500, 1)
+	conn := newTestConnection(t, fake, "")
+
+	_, err := conn.execute(testQuery{Table: "fleetdm_host", Columns: []string{"id"}})
+
+	assertErrorContains(t, err, "500 Internal Server Error")
+	assertErrorContains(t, err, "request id fleettest-500")
+}
+
+func TestListHostsRetriesUnavailable(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	fake.InjectError("hosts", 503, 1)
+	conn := newTestConnection(t, fake, "max_retries = 1")
+
+	rows := conn.rows(testQuery{Table: "fleetdm_host", Columns: []string{"id"}})
+
+	assertColumn(t, rows, "id", 5, 4, 3, 2, 1)
+	assertRequests(t, fake, "hosts", 2)
+}
+
+func TestListHostsUnauthorized(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnectionWithConfig(t, `server_url = "`+fake.URL+`"
+api_token = "wrong-token"
+`)
+
+	_, err := conn.execute(testQuery{Table: "fleetdm_host", Columns: []string{"id"}})
+
+	assertErrorContains(t, err, "401 Unauthorized: Authentication required")
+}
+
+func TestEmailPasswordLogin(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnectionWithConfig(t, `server_url = "`+fake.URL+`"
+email = "`+fleettest.Email+`"
+password = "`+fleettest.Password+`"
+`)
+
+	rows := conn.rows(testQuery{Table: "fleetdm_host", Columns: []string{"id"}})
+
+	assertColumn(t, rows, "id", 5, 4, 3, 2, 1)
+	assertRequests(t, fake, "login", 1)
+}
+
+func TestListHostsPopulatesSelectedColumnsOnly(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	conn.rows(testQuery{Table: "fleetdm_host", Columns: []string{"id", "hostname"}})
+	rows := conn.rows(testQuery{Table: "fleetdm_host", Columns: []string{"id", "policies"}, Quals: map[string]any{"status": "online"}})
+
+	requests := fake.Requests("hosts")
+	for _, param := range []string{"device_mapping", "populate_policies", "populate_users", "populate_labels"} {
+		if requests[0].Has(param) {
+			t.Errorf("%s sent although no JSON column was selected: %v", param, requests[0])
+		}
+	}
+	if requests[1].Get("populate_policies") != "true" {
+		t.Errorf("populate_policies not sent for the policies column: %v", requests[1])
+	}
+	for _, param := range []string{"device_mapping", "populate_users", "populate_labels"} {
+		if requests[1].Has(param) {
+			t.Errorf("%s sent although only policies was selected: %v", param, requests[1])
+		}
+	}
+	for _, r := range rows {
+		if r["id"] == int64(1) {
+			if policies, _ := r["policies"].([]any); len(policies) != 2 {
+				t.Errorf("policies = %v", r["policies"])
+			}
+		}
+	}
+}
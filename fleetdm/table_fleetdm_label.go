@@ -2,56 +2,27 @@ package fleetdm
 
 import (
 	"context"
-	"net/url"
-	"strconv"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleetapi"
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
-// Label represents a FleetDM label.
-// Refer to: https://fleetdm.com/docs/rest-api/rest-api#label-object
-type Label struct {
-	ID                  uint      `json:"id"`
-	CreatedAt           FleetTime `json:"created_at"`
-	UpdatedAt           FleetTime `json:"updated_at"`
-	Name                string    `json:"name"`
-	Description         string    `json:"description"`
-	Query               string    `json:"query"`                 // The SQL query for dynamic labeling
-	Platform            string    `json:"platform"`              // e.g., "darwin", "windows", "linux", "" for all
-	LabelType           string    `json:"label_type"`            // "regular" or "builtin"
-	LabelMembershipType string    `json:"label_membership_type"` // "dynamic" or "manual" (manual not via API yet for creation)
-	HostCount           int       `json:"host_count"`
-	DisplayText         string    `json:"display_text"` // Usually same as name
-	BuiltIn             bool      `json:"built_in"`     // Derived from label_type == "builtin"
-	// Hosts field is not typically included in list/get label, but on a separate endpoint like /labels/{id}/hosts
-}
-
-// ListLabelsResponse for `GET /api/v1/fleet/labels`
-// The API returns {"labels": [...]}
-type ListLabelsResponse struct {
-	Labels []Label `json:"labels"`
-	// Meta for pagination if API supports it
-}
-
-// GetLabelResponse for `GET /api/v1/fleet/labels/{id}`
-// The API returns {"label": {...}}
-type GetLabelResponse struct {
-	Label Label `json:"label"`
-}
-
 func tableFleetdmLabel(ctx context.Context) *plugin.Table {
 	return &plugin.Table{
-		Name:        "fleetdm_label",
-		Description: "Labels used for grouping hosts in FleetDM.",
+		Name:              "fleetdm_label",
+		Description:       "Labels used for grouping hosts in FleetDM.",
+		GetMatrixItemFunc: serverMatrix,
 		List: &plugin.ListConfig{
 			Hydrate: listLabels,
 			KeyColumns: []*plugin.KeyColumn{
 				{Name: "team_id", Require: plugin.Optional}, // Filter by team (Fleet Premium). Use 'global' for global-only labels.
+				{Name: "server", Require: plugin.Optional},  // Query only this Fleet server
 			},
 		},
-		Columns: []*plugin.Column{
+		Columns: withServerColumn([]*plugin.Column{
 			{Name: "id", Type: proto.ColumnType_INT, Description: "Unique ID of the label."},
 			{Name: "name", Type: proto.ColumnType_STRING, Description: "Name of the label."},
 			{Name: "display_text", Type: proto.ColumnType_STRING, Description: "Display text for the label, usually the same as the name."},
@@ -67,60 +38,36 @@ func tableFleetdmLabel(ctx context.Context) *plugin.Table {
 
 			// Query parameters that can be used for filtering (key columns)
 			{Name: "team_id", Type: proto.ColumnType_STRING, Transform: transform.FromQual("team_id"), Description: "Filter by team (Fleet Premium). Use 'global' for global-only labels. Set in WHERE clause."},
-		},
+		}),
 	}
 }
 
 func listLabels(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
-	client, err := NewFleetDMClient(ctx, d.Connection)
+	client, err := getClient(ctx, d)
 	if err != nil {
 		plugin.Logger(ctx).Error("fleetdm_label.listLabels", "connection_error", err)
 		return nil, err
 	}
 
-	// Pagination for labels: The /api/v1/fleet/labels endpoint supports `page` and `per_page`
-	page := 0
-	perPage := 50 // API default is 20, max 100
-
-	// limit := d.QueryContext.Limit
-	// if limit != nil && *limit < int64(perPage) {
-	// 	// perPage = int(*limit)
-	// }
-
-	for {
-		params := url.Values{}
-		params.Add("page", strconv.Itoa(page))
-		params.Add("per_page", strconv.Itoa(perPage))
-
-		if d.EqualsQuals["team_id"] != nil {
-			params.Add("team_id", d.EqualsQuals["team_id"].GetStringValue())
-		}
-
-		var response ListLabelsResponse
-		_, err := client.Get(ctx, "labels", params, &response)
-		if err != nil {
-			plugin.Logger(ctx).Error("fleetdm_label.listLabels", "api_error", err, "page", page, "params", params.Encode())
-			return nil, err
-		}
-
-		for _, label := range response.Labels {
-			d.StreamListItem(ctx, label)
-			if d.RowsRemaining(ctx) == 0 {
-				plugin.Logger(ctx).Debug("fleetdm_label.listLabels", "limit_reached", true)
-				return nil, nil
-			}
-		}
-
-		// Pagination check: if the number of labels returned is less than per_page,
-		// it's likely the last page. The /labels endpoint does not specify a `meta.has_next_results`.
-		if len(response.Labels) < perPage {
-			plugin.Logger(ctx).Debug("fleetdm_label.listLabels", "end_of_results", true, "labels_on_page", len(response.Labels))
-			break
-		}
+	if err := checkPremiumQuals(ctx, d, "fleetdm_label", "team_id"); err != nil {
+		return nil, err
+	}
 
-		page++
-		plugin.Logger(ctx).Debug("fleetdm_label.listLabels", "next_page", page)
+	opts := fleetapi.ListLabelsOptions{
+		ListOptions: listOptions(ctx, d),
+		TeamID:      qualString(d, "team_id"),
 	}
+	return nil, client.ListLabels(tableContext(ctx, d), opts, streamItems[fleetapi.Label](ctx, d))
+}
 
-	return nil, nil
+// collectLabels returns every label, for tables whose endpoints are queried per label.
+func collectLabels(ctx context.Context, d *plugin.QueryData, client *fleetapi.FleetDMClient) ([]fleetapi.Label, error) {
+	opts := fleetapi.ListLabelsOptions{ListOptions: listOptions(ctx, d)}
+	opts.Limit = 0 // The query's limit applies to the table's rows, not to the labels
+	var labels []fleetapi.Label
+	err := client.ListLabels(tableContext(ctx, d), opts, func(label fleetapi.Label) bool {
+		labels = append(labels, label)
+		return true
+	})
+	return labels, err
 }
@@ -0,0 +1,121 @@
+package fleetdm
+
+import (
+	"context"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleetapi"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// LabelHostRow is the membership of one host in one label.
+type LabelHostRow struct {
+	fleetapi.Host
+	LabelID             uint
+	LabelName           string
+	LabelMembershipType string
+}
+
+func tableFleetdmLabelHost(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:              "fleetdm_label_host",
+		Description:       "Hosts belonging to FleetDM labels, with one row per label and member host. Uses the /labels/:id/hosts endpoint.",
+		GetMatrixItemFunc: serverMatrix,
+		List: &plugin.ListConfig{
+			Hydrate: listLabelHosts,
+			Tags:    endpointTag(endpointFamilyHosts),
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "label_id", Require: plugin.Optional},     // Without it, every label is queried
+				{Name: "team_id", Require: plugin.Optional},      // Maps to API 'team_id' param
+				{Name: "status", Require: plugin.Optional},       // Maps to API 'status' param
+				{Name: "query", Require: plugin.Optional},        // Search by hostname, uuid, serial or IP
+				{Name: "cache_bypass", Require: plugin.Optional}, // Skip the response cache
+				{Name: "server", Require: plugin.Optional},       // Query only this Fleet server
+			},
+		},
+		Columns: withServerColumn([]*plugin.Column{
+			{Name: "label_id", Type: proto.ColumnType_INT, Description: "ID of the label."},
+			{Name: "label_name", Type: proto.ColumnType_STRING, Description: "Name of the label."},
+			{Name: "label_membership_type", Type: proto.ColumnType_STRING, Description: "How hosts become members of the label: 'dynamic' (by query) or 'manual'."},
+			{Name: "host_id", Type: proto.ColumnType_INT, Transform: transform.FromField("ID"), Description: "ID of the member host."},
+			{Name: "hostname", Type: proto.ColumnType_STRING, Description: "Hostname of the member host."},
+			{Name: "display_name", Type: proto.ColumnType_STRING, Description: "Display name of the member host."},
+			{Name: "uuid", Type: proto.ColumnType_STRING, Transform: transform.FromField("UUID"), Description: "UUID of the member host."},
+			{Name: "hardware_serial", Type: proto.ColumnType_STRING, Description: "Hardware serial number of the member host."},
+			{Name: "primary_ip", Type: proto.ColumnType_STRING, Transform: transform.FromField("PrimaryIP"), Description: "Primary IP address of the member host."},
+			{Name: "platform", Type: proto.ColumnType_STRING, Description: "Platform of the member host (e.g., 'darwin', 'windows', 'ubuntu')."},
+			{Name: "os_version", Type: proto.ColumnType_STRING, Description: "Operating system version of the member host."},
+			{Name: "status", Type: proto.ColumnType_STRING, Description: "Status of the member host (e.g., 'online', 'offline')."},
+			{Name: "team_id", Type: proto.ColumnType_INT, Description: "ID of the team the member host belongs to."},
+			{Name: "team_name", Type: proto.ColumnType_STRING, Description: "Name of the team the member host belongs to."},
+
+			// Query parameters that can be used for filtering (key columns)
+			{Name: "query", Type: proto.ColumnType_STRING, Transform: transform.FromQual("query"), Description: "Search query keywords. Searchable fields include hostname, uuid, hardware_serial and primary_ip. Set in WHERE clause."},
+			{Name: "cache_bypass", Type: proto.ColumnType_BOOL, Transform: transform.FromQual("cache_bypass"), Description: "Set to true to skip the response cache configured with cache_dir and fetch fresh data. Set in WHERE clause."},
+		}),
+	}
+}
+
+func listLabelHosts(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	ctx = cacheBypassContext(ctx, d)
+	client, err := getClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("fleetdm_label_host.listLabelHosts", "connection_error", err)
+		return nil, err
+	}
+
+	var labels []fleetapi.Label
+	if labelID := qualUint(d, "label_id"); labelID != nil {
+		label, err := client.GetLabel(tableContext(ctx, d), *labelID)
+		if err != nil {
+			if isNotFoundError(ctx, d, h, err) {
+				return nil, nil
+			}
+			plugin.Logger(ctx).Error("fleetdm_label_host.listLabelHosts", "api_error", err, "label_id", *labelID)
+			return nil, err
+		}
+		labels = append(labels, *label)
+	} else {
+		plugin.Logger(ctx).Info("fleetdm_label_host.listLabelHosts", "discovering_all_labels", true)
+		labels, err = collectLabels(ctx, d, client)
+		if err != nil {
+			plugin.Logger(ctx).Error("fleetdm_label_host.listLabelHosts", "labels_api_error", err)
+			return nil, err
+		}
+	}
+
+	for _, label := range labels {
+		// The options are built per label, so the limit covers only the rows still missing
+		opts := fleetapi.ListLabelHostsOptions{
+			ListOptions: listOptions(ctx, d),
+			Query:       qualString(d, "query"),
+			Status:      qualString(d, "status"),
+			TeamID:      qualUint(d, "team_id"),
+		}
+		err := client.ListLabelHosts(tableContext(ctx, d), label.ID, opts, func(host fleetapi.Host) bool {
+			d.StreamListItem(ctx, LabelHostRow{
+				Host:                host,
+				LabelID:             label.ID,
+				LabelName:           label.Name,
+				LabelMembershipType: label.LabelMembershipType,
+			})
+			return d.RowsRemaining(ctx) != 0
+		})
+		if err != nil {
+			if isNotFoundError(ctx, d, h, err) {
+				// The label was deleted after the labels were listed
+				plugin.Logger(ctx).Warn("fleetdm_label_host.listLabelHosts", "label_not_found", label.ID)
+				continue
+			}
+			plugin.Logger(ctx).Error("fleetdm_label_host.listLabelHosts", "api_error", err, "label_id", label.ID)
+			return nil, err
+		}
+		if d.RowsRemaining(ctx) == 0 {
+			plugin.Logger(ctx).Debug("fleetdm_label_host.listLabelHosts", "limit_reached", true)
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,136 @@
+package fleetdm_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListLabelHosts(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "page_size = 2")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_label_host",
+		Columns: []string{"label_id", "label_name", "label_membership_type", "host_id", "hostname", "platform", "team_id"},
+		Quals:   map[string]any{"label_id": 6},
+	})
+
+	assertColumn(t, rows, "host_id", 1, 2, 3, 4, 5)
+	assertColumn(t, rows, "label_name", "All Hosts", "All Hosts", "All Hosts", "All Hosts", "All Hosts")
+	assertRequests(t, fake, "labels/6", 1)
+	assertRequests(t, fake, "labels/6/hosts", 3)
+	assertRequests(t, fake, "labels", 0)
+	for _, r := range rows {
+		if r["host_id"] == int64(1) && (r["hostname"] != "alice-mbp.local" || r["platform"] != "darwin" || r["team_id"] != int64(1) || r["label_membership_type"] != "dynamic") {
+			t.Errorf("unexpected row for host 1: %v", r)
+		}
+	}
+}
+
+func TestListLabelHostsAllLabels(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_label_host",
+		Columns: []string{"label_id", "host_id"},
+	})
+
+	assertColumn(t, rows, "label_id", 6, 6, 6, 6, 6, 7, 12)
+	assertRequests(t, fake, "labels", 1)
+	for _, endpoint := range []string{"labels/6/hosts", "labels/7/hosts", "labels/12/hosts"} {
+		assertRequests(t, fake, endpoint, 1)
+	}
+}
+
+func TestListLabelHostsDeletedLabel(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+	// Label 7 is deleted between listing the labels and listing its hosts
+	fake.InjectError("labels/7/hosts", http.StatusNotFound, 0)
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_label_host",
+		Columns: []string{"label_id", "host_id"},
+	})
+
+	assertColumn(t, rows, "label_id", 6, 6, 6, 6, 6, 12)
+}
+
+func TestListLabelHostsFilters(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_label_host",
+		Columns: []string{"host_id"},
+		Quals:   map[string]any{"label_id": 6, "team_id": 2, "status": "online"},
+	})
+
+	params := fake.Requests("labels/6/hosts")[0]
+	if params.Get("team_id") != "2" || params.Get("status") != "online" {
+		t.Errorf("request params = %v", params)
+	}
+	for _, r := range rows {
+		if r["host_id"] != int64(3) && r["host_id"] != int64(4) {
+			t.Errorf("host %v is not in team 2", r["host_id"])
+		}
+	}
+}
+
+func TestListLabelHostsLimit(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_label_host",
+		Columns: []string{"host_id"},
+		Quals:   map[string]any{"label_id": 6},
+		Limit:   2,
+	})
+
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	if params := fake.Requests("labels/6/hosts")[0]; params.Get("per_page") != "2" {
+		t.Errorf("request params = %v", params)
+	}
+}
+
+func TestListLabelHostsManualLabel(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	fake.InjectResponse("labels/20", http.StatusOK, `{"label": {"id": 20, "name": "Executives", "label_type": "regular", "label_membership_type": "manual"}}`, 1)
+	fake.InjectResponse("labels/20/hosts", http.StatusOK, `{"hosts": [{"id": 2, "hostname": "bob-thinkpad"}]}`, 1)
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_label_host",
+		Columns: []string{"label_name", "label_membership_type", "host_id", "hostname"},
+		Quals:   map[string]any{"label_id": 20},
+	})
+
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	if r := rows[0]; r["label_name"] != "Executives" || r["label_membership_type"] != "manual" || r["host_id"] != int64(2) {
+		t.Errorf("unexpected row: %v", r)
+	}
+}
+
+func TestListLabelHostsUnknownLabel(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_label_host",
+		Columns: []string{"host_id"},
+		Quals:   map[string]any{"label_id": 999},
+	})
+
+	if len(rows) != 0 {
+		t.Errorf("got %d rows, want none", len(rows))
+	}
+	assertRequests(t, fake, "labels/999/hosts", 0)
+}
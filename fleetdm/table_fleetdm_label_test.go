@@ -0,0 +1,48 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListLabels(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "page_size = 2")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_label",
+		Columns: []string{"id", "name", "query_sql", "label_type", "host_count", "updated_at"},
+	})
+
+	assertColumn(t, rows, "name", "All Hosts", "macOS", "Low disk")
+	assertColumn(t, rows, "label_type", "builtin", "builtin", "regular")
+	assertColumn(t, rows, "host_count", 5, 1, 1)
+	assertRequests(t, fake, "labels", 2)
+}
+
+func TestListLabelsForTeam(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_label",
+		Columns: []string{"id", "team_id"},
+		Quals:   map[string]any{"team_id": "global"},
+	})
+
+	assertColumn(t, rows, "id", 6, 7)
+}
+
+func TestListLabelsPremiumQualOnFreeTier(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{Tier: fleettest.TierFree})
+	conn := newTestConnection(t, fake, "")
+
+	_, err := conn.execute(testQuery{
+		Table:   "fleetdm_label",
+		Columns: []string{"id"},
+		Quals:   map[string]any{"team_id": "1"},
+	})
+
+	assertErrorContains(t, err, "the team_id qual requires Fleet Premium")
+}
@@ -0,0 +1,50 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListOSVersions(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "page_size = 2")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_os_version",
+		Columns: []string{"os_version_id", "name", "hosts_count", "vulnerabilities_count", "generated_cpes", "vulnerabilities"},
+	})
+
+	assertColumn(t, rows, "os_version_id", 21, 20, 22)
+	// The plugin maps zero values to null
+	assertColumn(t, rows, "vulnerabilities_count", nil, 1, nil)
+	assertRequests(t, fake, "os_versions", 2)
+	for _, r := range rows {
+		if r["os_version_id"] != int64(20) {
+			continue
+		}
+		vulnerabilities, _ := r["vulnerabilities"].([]any)
+		if len(vulnerabilities) != 1 {
+			t.Fatalf("vulnerabilities = %v", r["vulnerabilities"])
+		}
+		if cve, _ := vulnerabilities[0].(map[string]any); cve["cve"] != "CVE-2024-27834" {
+			t.Errorf("vulnerability = %v", cve)
+		}
+	}
+}
+
+func TestListOSVersionsQuals(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_os_version",
+		Columns: []string{"os_version_id", "platform"},
+		Quals:   map[string]any{"platform": "darwin"},
+	})
+
+	assertColumn(t, rows, "os_version_id", 20)
+	if params := fake.Requests("os_versions")[0]; params.Get("platform") != "darwin" {
+		t.Errorf("platform param not sent: %v", params)
+	}
+}
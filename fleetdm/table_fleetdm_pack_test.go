@@ -0,0 +1,22 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListPacks(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_pack",
+		Columns: []string{"id", "name", "platform", "disabled", "type", "target_count", "total_scheduled_queries_count"},
+	})
+
+	assertColumn(t, rows, "name", "Global Pack", "incident-response")
+	assertColumn(t, rows, "disabled", false, true)
+	assertColumn(t, rows, "target_count", 5, nil)
+	assertRequests(t, fake, "packs", 1)
+}
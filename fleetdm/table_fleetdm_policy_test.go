@@ -0,0 +1,64 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListGlobalPolicies(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_policy",
+		Columns: []string{"id", "name", "query_text", "team_id", "critical", "passing_host_count", "failing_host_count"},
+	})
+
+	assertColumn(t, rows, "id", 1, 2)
+	assertColumn(t, rows, "critical", true, false)
+	assertColumn(t, rows, "team_id", nil, nil)
+	assertRequests(t, fake, "global/policies", 1)
+}
+
+func TestListTeamPolicies(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_policy",
+		Columns: []string{"id", "team_id", "calendar_events_enabled"},
+		Quals:   map[string]any{"team_id": 1},
+	})
+
+	assertColumn(t, rows, "id", 3)
+	assertColumn(t, rows, "calendar_events_enabled", true)
+	assertRequests(t, fake, "teams/1/policies", 1)
+	assertRequests(t, fake, "global/policies", 0)
+}
+
+func TestListTeamPoliciesMergeInherited(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_policy",
+		Columns: []string{"id"},
+		Quals:   map[string]any{"team_id": 2, "merge_inherited": true},
+	})
+
+	assertColumn(t, rows, "id", 1, 2, 4)
+}
+
+func TestListTeamPoliciesOnFreeTier(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{Tier: fleettest.TierFree})
+	conn := newTestConnection(t, fake, "")
+
+	_, err := conn.execute(testQuery{
+		Table:   "fleetdm_policy",
+		Columns: []string{"id"},
+		Quals:   map[string]any{"team_id": 1},
+	})
+
+	assertErrorContains(t, err, "the team_id qual requires Fleet Premium")
+}
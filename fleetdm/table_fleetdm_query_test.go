@@ -0,0 +1,60 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListQueries(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "page_size = 2")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_query",
+		Columns: []string{"id", "name", "query_sql", "interval", "logging_type", "stats", "packs", "created_at"},
+	})
+
+	assertColumn(t, rows, "id", 1, 2, 3)
+	assertColumn(t, rows, "logging_type", "snapshot", "differential", "snapshot")
+	assertColumn(t, rows, "interval", 3600, 86400, nil)
+	assertColumn(t, rows, "created_at", "2024-01-02T00:00:00Z", "2024-01-03T00:00:00Z", "2024-02-03T00:00:00Z")
+	assertRequests(t, fake, "queries", 2)
+	for _, r := range rows {
+		if r["id"] != int64(1) {
+			continue
+		}
+		if packs, _ := r["packs"].([]any); len(packs) != 1 {
+			t.Errorf("packs = %v", r["packs"])
+		}
+		if stats, _ := r["stats"].(map[string]any); stats["total_executions"] != float64(12) {
+			t.Errorf("stats = %v", r["stats"])
+		}
+	}
+}
+
+func TestListQueriesForTeam(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_query",
+		Columns: []string{"id", "team_id"},
+		Quals:   map[string]any{"team_id": 1},
+	})
+
+	assertColumn(t, rows, "id", 3)
+}
+
+func TestListQueriesPremiumQualOnFreeTier(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{Tier: fleettest.TierFree})
+	conn := newTestConnection(t, fake, "")
+
+	_, err := conn.execute(testQuery{
+		Table:   "fleetdm_query",
+		Columns: []string{"id"},
+		Quals:   map[string]any{"merge_inherited": true},
+	})
+
+	assertErrorContains(t, err, "the merge_inherited qual requires Fleet Premium")
+}
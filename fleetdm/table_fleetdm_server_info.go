@@ -0,0 +1,48 @@
+package fleetdm
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+func tableFleetdmServerInfo(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:              "fleetdm_server_info",
+		Description:       "Version and license of the FleetDM server, as detected by the plugin to enable or reject version and Premium dependent features. Uses the /version and /config endpoints.",
+		GetMatrixItemFunc: serverMatrix,
+		List: &plugin.ListConfig{
+			Hydrate: listServerInfo,
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "server", Require: plugin.Optional}, // Query only this Fleet server
+			},
+		},
+		Columns: withServerColumn([]*plugin.Column{
+			{Name: "server_url", Type: proto.ColumnType_STRING, Transform: transform.FromField("ServerURL"), Description: "URL of the FleetDM server."},
+			{Name: "version", Type: proto.ColumnType_STRING, Description: "Fleet server version (e.g., '4.62.1')."},
+			{Name: "branch", Type: proto.ColumnType_STRING, Description: "Git branch the server was built from."},
+			{Name: "revision", Type: proto.ColumnType_STRING, Description: "Git revision the server was built from."},
+			{Name: "go_version", Type: proto.ColumnType_STRING, Description: "Go version used to build the server."},
+			{Name: "build_date", Type: proto.ColumnType_STRING, Description: "Date the server was built."},
+			{Name: "build_user", Type: proto.ColumnType_STRING, Description: "User that built the server."},
+			{Name: "license_tier", Type: proto.ColumnType_STRING, Description: "License tier of the server: 'free' or 'premium'."},
+			{Name: "premium", Type: proto.ColumnType_BOOL, Transform: transform.FromMethod("IsPremium"), Description: "True if the server runs with a Fleet Premium license. Premium-only tables return no rows and Premium-only key columns are rejected otherwise."},
+			{Name: "license_organization", Type: proto.ColumnType_STRING, Description: "Organization the license was issued to."},
+			{Name: "license_device_count", Type: proto.ColumnType_INT, Description: "Number of devices covered by the license."},
+			{Name: "license_expiration", Type: proto.ColumnType_TIMESTAMP, Transform: transform.FromField("LicenseExpiration").Transform(flexibleTimeTransform), Description: "Timestamp when the license expires."},
+			{Name: "org_name", Type: proto.ColumnType_STRING, Description: "Organization name configured in Fleet."},
+		}),
+	}
+}
+
+func listServerInfo(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	info, err := getServerInfo(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("fleetdm_server_info.listServerInfo", "api_error", err)
+		return nil, err
+	}
+	d.StreamListItem(ctx, info)
+	return nil, nil
+}
@@ -0,0 +1,48 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListServerInfo(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_server_info",
+		Columns: []string{"server_url", "version", "license_tier", "premium", "license_expiration", "org_name"},
+	})
+
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	want := row{
+		"server_url":         fake.URL,
+		"version":            fleettest.DefaultVersion,
+		"license_tier":       "premium",
+		"premium":            true,
+		"license_expiration": "2030-01-01T00:00:00Z",
+		"org_name":           "Fleet Test",
+	}
+	for column, value := range want {
+		if rows[0][column] != value {
+			t.Errorf("%s = %v, want %v", column, rows[0][column], value)
+		}
+	}
+}
+
+func TestListServerInfoFreeTier(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{Tier: fleettest.TierFree, Version: "4.48.0"})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_server_info",
+		Columns: []string{"version", "license_tier", "premium"},
+	})
+
+	assertColumn(t, rows, "version", "4.48.0")
+	assertColumn(t, rows, "license_tier", "free")
+	assertColumn(t, rows, "premium", false)
+}
@@ -2,58 +2,22 @@ package fleetdm
 
 import (
 	"context"
-	"net/url"
-	"strconv"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleetapi"
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
-// SoftwareTitleVersion represents a version entry within a software title.
-type SoftwareTitleVersion struct {
-	ID              uint     `json:"id"`
-	Version         string   `json:"version"`
-	Vulnerabilities []string `json:"vulnerabilities"` // List of CVE strings
-	HostsCount      *uint    `json:"hosts_count,omitempty"`
-}
-
-// SoftwareTitle represents a software title in FleetDM.
-// Refer to: https://fleetdm.com/docs/rest-api/rest-api#list-software
-type SoftwareTitle struct {
-	ID               uint                   `json:"id"`
-	Name             string                 `json:"name"`
-	DisplayName      string                 `json:"display_name"`
-	IconURL          *string                `json:"icon_url"`
-	Source           string                 `json:"source"`
-	ExtensionFor     string                 `json:"extension_for"`
-	Browser          string                 `json:"browser"`
-	HostsCount       uint                   `json:"hosts_count"`
-	VersionsCount    uint                   `json:"versions_count"`
-	Versions         []SoftwareTitleVersion `json:"versions"`
-	SoftwarePackage  interface{}            `json:"software_package"`
-	AppStoreApp      interface{}            `json:"app_store_app"`
-	BundleIdentifier *string                `json:"bundle_identifier"`
-	CountsUpdatedAt  *FleetTime             `json:"counts_updated_at"`
-}
-
-// ListSoftwareTitlesResponse is the expected structure for the list software titles API call.
-type ListSoftwareTitlesResponse struct {
-	SoftwareTitles []SoftwareTitle `json:"software_titles"`
-	Meta           struct {
-		HasNextResults     bool `json:"has_next_results"`
-		HasPreviousResults bool `json:"has_previous_results"`
-	} `json:"meta"`
-	Count           int        `json:"count"`
-	CountsUpdatedAt *FleetTime `json:"counts_updated_at"`
-}
-
 func tableFleetdmSoftwareTitle(ctx context.Context) *plugin.Table {
 	return &plugin.Table{
-		Name:        "fleetdm_software_title",
-		Description: "Software titles from FleetDM. A software title groups multiple versions of the same software. Uses the /software/titles endpoint.",
+		Name:              "fleetdm_software_title",
+		Description:       "Software titles from FleetDM. A software title groups multiple versions of the same software. Uses the /software/titles endpoint.",
+		GetMatrixItemFunc: serverMatrix,
 		List: &plugin.ListConfig{
 			Hydrate: listSoftwareTitles,
+			Tags:    endpointTag(endpointFamilySoftware),
 			KeyColumns: []*plugin.KeyColumn{
 				{Name: "vulnerable_only", Require: plugin.Optional},               // Filter for vulnerable software
 				{Name: "team_id", Require: plugin.Optional},                       // Filter by team ID (Fleet Premium)
@@ -66,9 +30,11 @@ func tableFleetdmSoftwareTitle(ctx context.Context) *plugin.Table {
 				{Name: "exploit", Require: plugin.Optional},                       // Filter for CISA known exploits (Fleet Premium)
 				{Name: "platform", Require: plugin.Optional},                      // Filter by platform (requires team_id)
 				{Name: "exclude_fleet_maintained_apps", Require: plugin.Optional}, // Exclude Fleet-maintained apps
+				{Name: "cache_bypass", Require: plugin.Optional},                  // Skip the response cache
+				{Name: "server", Require: plugin.Optional},                        // Query only this Fleet server
 			},
 		},
-		Columns: []*plugin.Column{
+		Columns: withServerColumn([]*plugin.Column{
 			// Core software title information
 			{Name: "id", Type: proto.ColumnType_INT, Description: "Unique ID of the software title."},
 			{Name: "name", Type: proto.ColumnType_STRING, Description: "Name of the software title."},
@@ -98,104 +64,36 @@ func tableFleetdmSoftwareTitle(ctx context.Context) *plugin.Table {
 			{Name: "exploit", Type: proto.ColumnType_BOOL, Transform: transform.FromQual("exploit"), Description: "Filter for software with vulnerabilities that have been actively exploited in the wild — CISA known exploit (Fleet Premium). Set in WHERE clause."},
 			{Name: "platform", Type: proto.ColumnType_STRING, Transform: transform.FromQual("platform"), Description: "Filter installable titles by platform. Options: 'macos', 'darwin', 'windows', 'linux', 'chrome', 'ios', 'ipados'. Requires team_id. Set in WHERE clause."},
 			{Name: "exclude_fleet_maintained_apps", Type: proto.ColumnType_BOOL, Transform: transform.FromQual("exclude_fleet_maintained_apps"), Description: "Exclude Fleet-maintained apps from the results. Set in WHERE clause."},
-		},
+			{Name: "cache_bypass", Type: proto.ColumnType_BOOL, Transform: transform.FromQual("cache_bypass"), Description: "Set to true to skip the response cache configured with cache_dir and fetch fresh data. Set in WHERE clause."},
+		}),
 	}
 }
 
 func listSoftwareTitles(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
-	client, err := NewFleetDMClient(ctx, d.Connection)
+	ctx = cacheBypassContext(ctx, d)
+	client, err := getClient(ctx, d)
 	if err != nil {
 		plugin.Logger(ctx).Error("fleetdm_software_title.listSoftwareTitles", "connection_error", err)
 		return nil, err
 	}
 
-	page := 0
-	perPage := 10000
-
-	for {
-		params := url.Values{}
-		params.Add("page", strconv.Itoa(page))
-		params.Add("per_page", strconv.Itoa(perPage))
-		params.Add("order_key", "hosts_count")
-		params.Add("order_direction", "desc")
-
-		if d.EqualsQuals["vulnerable_only"] != nil {
-			params.Add("vulnerable", strconv.FormatBool(d.EqualsQuals["vulnerable_only"].GetBoolValue()))
-		}
-		if d.EqualsQuals["team_id"] != nil {
-			params.Add("team_id", strconv.FormatInt(d.EqualsQuals["team_id"].GetInt64Value(), 10))
-		}
-		if d.EqualsQuals["available_for_install"] != nil {
-			params.Add("available_for_install", strconv.FormatBool(d.EqualsQuals["available_for_install"].GetBoolValue()))
-		}
-		if d.EqualsQuals["query"] != nil {
-			params.Add("query", d.EqualsQuals["query"].GetStringValue())
-		}
-		if d.EqualsQuals["self_service"] != nil {
-			params.Add("self_service", strconv.FormatBool(d.EqualsQuals["self_service"].GetBoolValue()))
-		}
-		if d.EqualsQuals["packages_only"] != nil {
-			params.Add("packages_only", strconv.FormatBool(d.EqualsQuals["packages_only"].GetBoolValue()))
-		}
-
-		// The API requires vulnerable=true when using min_cvss_score, max_cvss_score, or exploit.
-		// Auto-set vulnerable=true if any of these are specified and vulnerable_only was not explicitly set.
-		hasCVSSOrExploit := d.EqualsQuals["min_cvss_score"] != nil || d.EqualsQuals["max_cvss_score"] != nil || d.EqualsQuals["exploit"] != nil
-		if hasCVSSOrExploit && d.EqualsQuals["vulnerable_only"] == nil {
-			params.Add("vulnerable", "true")
-		}
-
-		if d.EqualsQuals["min_cvss_score"] != nil {
-			params.Add("min_cvss_score", strconv.FormatInt(d.EqualsQuals["min_cvss_score"].GetInt64Value(), 10))
-		}
-		if d.EqualsQuals["max_cvss_score"] != nil {
-			params.Add("max_cvss_score", strconv.FormatInt(d.EqualsQuals["max_cvss_score"].GetInt64Value(), 10))
-		}
-		if d.EqualsQuals["exploit"] != nil {
-			params.Add("exploit", strconv.FormatBool(d.EqualsQuals["exploit"].GetBoolValue()))
-		}
-		if d.EqualsQuals["platform"] != nil {
-			params.Add("platform", d.EqualsQuals["platform"].GetStringValue())
-		}
-		if d.EqualsQuals["exclude_fleet_maintained_apps"] != nil {
-			params.Add("exclude_fleet_maintained_apps", strconv.FormatBool(d.EqualsQuals["exclude_fleet_maintained_apps"].GetBoolValue()))
-		}
-
-		var response ListSoftwareTitlesResponse
-		_, err := client.Get(ctx, "software/titles", params, &response) // Endpoint is /api/v1/fleet/software/titles
-		if err != nil {
-			plugin.Logger(ctx).Error("fleetdm_software_title.listSoftwareTitles", "api_error", err, "page", page, "params", params.Encode())
-			return nil, err
-		}
-
-		for _, title := range response.SoftwareTitles {
-			d.StreamListItem(ctx, title)
-			if d.RowsRemaining(ctx) == 0 {
-				plugin.Logger(ctx).Debug("fleetdm_software_title.listSoftwareTitles", "limit_reached_sdk", "true")
-				return nil, nil
-			}
-		}
-
-		plugin.Logger(ctx).Info("fleetdm_software_title.listSoftwareTitles",
-			"page_processed", page,
-			"items_on_page", len(response.SoftwareTitles),
-			"api_total_count", response.Count,
-			"api_has_next_results", response.Meta.HasNextResults,
-		)
-
-		if len(response.SoftwareTitles) < perPage {
-			plugin.Logger(ctx).Info("fleetdm_software_title.listSoftwareTitles", "pagination_ended_item_count_less_than_per_page", true, "current_page", page, "items_on_page", len(response.SoftwareTitles), "per_page", perPage)
-			break
-		}
-
-		if !response.Meta.HasNextResults && len(response.SoftwareTitles) == perPage {
-			plugin.Logger(ctx).Warn("fleetdm_software_title.listSoftwareTitles", "api_has_next_results_is_false_but_full_page_received", true, "current_page", page)
-		}
-
-		page++
-		plugin.Logger(ctx).Debug("fleetdm_software_title.listSoftwareTitles", "incrementing_to_next_page", page)
+	if err := checkPremiumQuals(ctx, d, "fleetdm_software_title", "packages_only", "min_cvss_score", "max_cvss_score", "exploit"); err != nil {
+		return nil, err
 	}
 
-	plugin.Logger(ctx).Info("fleetdm_software_title.listSoftwareTitles", "list_software_titles_completed", true)
-	return nil, nil
+	opts := fleetapi.ListSoftwareTitlesOptions{
+		ListOptions:                listOptions(ctx, d),
+		Vulnerable:                 qualBool(d, "vulnerable_only"),
+		TeamID:                     qualUint(d, "team_id"),
+		AvailableForInstall:        qualBool(d, "available_for_install"),
+		Query:                      qualString(d, "query"),
+		SelfService:                qualBool(d, "self_service"),
+		PackagesOnly:               qualBool(d, "packages_only"),
+		MinCVSSScore:               qualInt(d, "min_cvss_score"),
+		MaxCVSSScore:               qualInt(d, "max_cvss_score"),
+		Exploit:                    qualBool(d, "exploit"),
+		Platform:                   qualString(d, "platform"),
+		ExcludeFleetMaintainedApps: qualBool(d, "exclude_fleet_maintained_apps"),
+	}
+	return nil, client.ListSoftwareTitles(tableContext(ctx, d), opts, streamItems[fleetapi.SoftwareTitle](ctx, d))
 }
@@ -0,0 +1,63 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListSoftwareTitles(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "page_size = 2")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_software_title",
+		Columns: []string{"id", "name", "hosts_count", "versions_count", "bundle_identifier", "versions", "software_package"},
+	})
+
+	assertColumn(t, rows, "id", 10, 11, 12)
+	assertColumn(t, rows, "hosts_count", 2, 1, 1)
+	assertColumn(t, rows, "bundle_identifier", nil, "com.google.Chrome", nil)
+	assertRequests(t, fake, "software/titles", 2)
+	for _, r := range rows {
+		if r["id"] != int64(11) {
+			continue
+		}
+		if versions, _ := r["versions"].([]any); len(versions) != 1 {
+			t.Errorf("versions = %v", r["versions"])
+		}
+		if pkg, _ := r["software_package"].(map[string]any); pkg["self_service"] != true {
+			t.Errorf("software_package = %v", r["software_package"])
+		}
+	}
+}
+
+func TestListSoftwareTitlesQuals(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_software_title",
+		Columns: []string{"id", "name"},
+		Quals:   map[string]any{"vulnerable_only": true, "query": "chrome"},
+	})
+
+	assertColumn(t, rows, "id", 11)
+	params := fake.Requests("software/titles")[0]
+	if params.Get("vulnerable") != "true" || params.Get("query") != "chrome" {
+		t.Errorf("quals not sent as params: %v", params)
+	}
+}
+
+func TestListSoftwareTitlesPremiumQualOnFreeTier(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{Tier: fleettest.TierFree})
+	conn := newTestConnection(t, fake, "")
+
+	_, err := conn.execute(testQuery{
+		Table:   "fleetdm_software_title",
+		Columns: []string{"id"},
+		Quals:   map[string]any{"packages_only": true},
+	})
+
+	assertErrorContains(t, err, "the packages_only qual requires Fleet Premium")
+}
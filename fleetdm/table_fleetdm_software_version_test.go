@@ -0,0 +1,66 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListSoftwareVersions(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "page_size = 2")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_software_version",
+		Columns: []string{"id", "name", "version", "host_count", "arch", "last_opened_at", "vulnerabilities"},
+	})
+
+	assertColumn(t, rows, "id", 1, 2, 3)
+	assertColumn(t, rows, "host_count", 1, 2, 1)
+	assertColumn(t, rows, "arch", nil, "amd64", nil)
+	assertColumn(t, rows, "last_opened_at", nil, nil, "2024-05-30T17:00:00Z")
+	// meta.has_next_results ends the walk, so no empty trailing page is requested
+	assertRequests(t, fake, "software/versions", 2)
+	for _, r := range rows {
+		if r["id"] != int64(1) {
+			continue
+		}
+		vulnerabilities, _ := r["vulnerabilities"].([]any)
+		if len(vulnerabilities) != 1 {
+			t.Fatalf("vulnerabilities = %v", r["vulnerabilities"])
+		}
+		if cve, _ := vulnerabilities[0].(map[string]any); cve["cve"] != "CVE-2024-5274" || cve["cvss_score"] != 8.8 {
+			t.Errorf("vulnerability = %v", cve)
+		}
+	}
+}
+
+func TestListSoftwareVersionsVulnerableOnly(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_software_version",
+		Columns: []string{"id", "name"},
+		Quals:   map[string]any{"vulnerable_only": true},
+	})
+
+	assertColumn(t, rows, "name", "Google Chrome.app")
+	if params := fake.Requests("software/versions")[0]; params.Get("vulnerable") != "true" {
+		t.Errorf("vulnerable param not sent: %v", params)
+	}
+}
+
+func TestListSoftwareVersionsPremiumQualOnFreeTier(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{Tier: fleettest.TierFree})
+	conn := newTestConnection(t, fake, "")
+
+	_, err := conn.execute(testQuery{
+		Table:   "fleetdm_software_version",
+		Columns: []string{"id"},
+		Quals:   map[string]any{"exploit": true},
+	})
+
+	assertErrorContains(t, err, "the exploit qual requires Fleet Premium")
+	assertRequests(t, fake, "software/versions", 0)
+}
@@ -0,0 +1,58 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListTeams(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "page_size = 1")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_team",
+		Columns: []string{"id", "name", "user_count", "host_count", "created_at", "secrets", "agent_options"},
+	})
+
+	assertColumn(t, rows, "name", "Workstations", "Servers")
+	assertColumn(t, rows, "created_at", "2024-01-05T00:00:00Z", "2024-01-06T00:00:00Z")
+	// Pages are counted, so a full last page costs one more, empty, request
+	assertRequests(t, fake, "teams", 3)
+	for _, r := range rows {
+		if r["id"] != int64(1) {
+			continue
+		}
+		if secrets, _ := r["secrets"].([]any); len(secrets) != 1 {
+			t.Errorf("secrets = %v", r["secrets"])
+		}
+		if options, _ := r["agent_options"].(map[string]any); options["config"] == nil {
+			t.Errorf("agent_options = %v", r["agent_options"])
+		}
+	}
+}
+
+func TestListTeamsQuery(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_team",
+		Columns: []string{"id", "name"},
+		Quals:   map[string]any{"query": "serv"},
+	})
+
+	assertColumn(t, rows, "id", 2)
+}
+
+func TestListTeamsOnFreeTier(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{Tier: fleettest.TierFree})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{Table: "fleetdm_team", Columns: []string{"id"}})
+
+	if len(rows) != 0 {
+		t.Errorf("got %d rows on the free tier, want 0", len(rows))
+	}
+	assertRequests(t, fake, "teams", 0)
+}
@@ -2,56 +2,28 @@ package fleetdm
 
 import (
 	"context"
-	"net/url"
-	"strconv"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleetapi"
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
-// User represents a FleetDM user.
-// Refer to: https://fleetdm.com/docs/rest-api/rest-api#user-object
-type User struct {
-	ID                       uint       `json:"id"`
-	CreatedAt                FleetTime  `json:"created_at"`
-	UpdatedAt                FleetTime  `json:"updated_at"`
-	Name                     string     `json:"name"`
-	Email                    string     `json:"email"`
-	AdminForcedPasswordReset bool       `json:"admin_forced_password_reset"`
-	GravatarURL              string     `json:"gravatar_url"`
-	SSOEnabled               bool       `json:"sso_enabled"`
-	GlobalRole               *string    `json:"global_role"` // e.g., "admin", "maintainer", "observer"
-	Teams                    []UserTeam `json:"teams"`       // Teams the user belongs to and their role in each
-	APIOnly                  bool       `json:"api_only"`    // True if the user is an API-only user
-}
-
-// UserTeam represents a team a user belongs to and their role.
-type UserTeam struct {
-	ID   uint   `json:"id"`
-	Name string `json:"name"`
-	Role string `json:"role"` // Role within the team, e.g., "admin", "maintainer", "observer"
-}
-
-// ListUsersResponse is the structure for the list users API response.
-// The API doc (https://fleetdm.com/docs/rest-api/rest-api#list-all-users) shows a response like:
-// { "users": [ { ...user_object... } ] }
-type ListUsersResponse struct {
-	Users []User `json:"users"`
-}
-
 func tableFleetdmUser(ctx context.Context) *plugin.Table {
 	return &plugin.Table{
-		Name:        "fleetdm_user",
-		Description: "Information about users in FleetDM.",
+		Name:              "fleetdm_user",
+		Description:       "Information about users in FleetDM.",
+		GetMatrixItemFunc: serverMatrix,
 		List: &plugin.ListConfig{
 			Hydrate: listUsers,
 			KeyColumns: []*plugin.KeyColumn{
 				{Name: "query", Require: plugin.Optional},   // Search by name or email
 				{Name: "team_id", Require: plugin.Optional}, // Filter by team (Fleet Premium)
+				{Name: "server", Require: plugin.Optional},  // Query only this Fleet server
 			},
 		},
-		Columns: []*plugin.Column{
+		Columns: withServerColumn([]*plugin.Column{
 			{Name: "id", Type: proto.ColumnType_INT, Description: "Unique ID of the user."},
 			{Name: "name", Type: proto.ColumnType_STRING, Description: "Full name of the user."},
 			{Name: "email", Type: proto.ColumnType_STRING, Description: "Email address of the user."},
@@ -67,79 +39,25 @@ func tableFleetdmUser(ctx context.Context) *plugin.Table {
 			// Query parameters that can be used for filtering (key columns)
 			{Name: "query", Type: proto.ColumnType_STRING, Transform: transform.FromQual("query"), Description: "Search query keywords. Searchable fields include name and email. Set in WHERE clause."},
 			{Name: "team_id", Type: proto.ColumnType_INT, Transform: transform.FromQual("team_id"), Description: "Filter by team ID (Fleet Premium). Set in WHERE clause."},
-		},
+		}),
 	}
 }
 
 func listUsers(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
-	client, err := NewFleetDMClient(ctx, d.Connection)
+	client, err := getClient(ctx, d)
 	if err != nil {
 		plugin.Logger(ctx).Error("fleetdm_user.listUsers", "connection_error", err)
 		return nil, err
 	}
 
-	// Pagination for users: The /api/v1/fleet/users endpoint supports `page` and `per_page`
-	page := 0
-	perPage := 50 // A reasonable default, adjust as needed or if API has specific limits/max
-
-	// limit := d.QueryContext.Limit
-	// if limit != nil && *limit < int64(perPage) {
-	// 	// perPage = int(*limit) // Be cautious if API has minimum per_page
-	// }
-
-	for {
-		params := url.Values{}
-		params.Add("page", strconv.Itoa(page))
-		params.Add("per_page", strconv.Itoa(perPage))
-
-		if d.EqualsQuals["query"] != nil {
-			params.Add("query", d.EqualsQuals["query"].GetStringValue())
-		}
-		if d.EqualsQuals["team_id"] != nil {
-			params.Add("team_id", strconv.FormatInt(d.EqualsQuals["team_id"].GetInt64Value(), 10))
-		}
-
-		var usersResponse ListUsersResponse
-
-		httpResp, err := client.Get(ctx, "users", params, &usersResponse) // Pass ListUsersResponse struct
-		if err != nil {
-			plugin.Logger(ctx).Error("fleetdm_user.listUsers", "api_error", err, "page", page)
-			return nil, err
-		}
-
-		// Check if usersResponse.Users is nil after a successful HTTP call, which might indicate an empty list or unexpected response format
-		if httpResp.StatusCode == 200 && usersResponse.Users == nil {
-			// This could happen if the API returned `[]` instead of `{"users": []}` and the decoder didn't error but also didn't populate.
-			// Or if it returned `{"users": null}`.
-			// Given the API docs, `{"users": [...]}` is expected, so `usersResponse.Users` should be populated.
-			// If it's nil, it implies an empty list of users from the API for this page.
-			plugin.Logger(ctx).Debug("fleetdm_user.listUsers", "users_array_is_nil_or_empty_on_page", page)
-			// This is a valid state for the last page if it's empty, or if there are no users.
-		}
-
-		if len(usersResponse.Users) == 0 && page == 0 { // No users found at all on the first page
-			plugin.Logger(ctx).Debug("fleetdm_user.listUsers", "no_users_found_at_all", true)
-			return nil, nil // Stop if no users on the very first call
-		}
-
-		for _, user := range usersResponse.Users {
-			d.StreamListItem(ctx, user)
-			if d.RowsRemaining(ctx) == 0 {
-				plugin.Logger(ctx).Debug("fleetdm_user.listUsers", "limit_reached", true)
-				return nil, nil
-			}
-		}
-
-		// Pagination check: if the number of users returned is less than per_page,
-		// it's the last page. FleetDM's /users endpoint does not seem to use a 'meta.has_next_results' field.
-		if len(usersResponse.Users) < perPage {
-			plugin.Logger(ctx).Debug("fleetdm_user.listUsers", "end_of_results", true, "users_on_page", len(usersResponse.Users))
-			break
-		}
-
-		page++
-		plugin.Logger(ctx).Debug("fleetdm_user.listUsers", "next_page", page)
+	if err := checkPremiumQuals(ctx, d, "fleetdm_user", "team_id"); err != nil {
+		return nil, err
 	}
 
-	return nil, nil
+	opts := fleetapi.ListUsersOptions{
+		ListOptions: listOptions(ctx, d),
+		Query:       qualString(d, "query"),
+		TeamID:      qualUint(d, "team_id"),
+	}
+	return nil, client.ListUsers(tableContext(ctx, d), opts, streamItems[fleetapi.User](ctx, d))
 }
@@ -0,0 +1,35 @@
+package fleetdm_test
+
+import (
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+func TestListUsers(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "page_size = 2")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_user",
+		Columns: []string{"id", "email", "global_role", "api_only", "sso_enabled", "teams"},
+	})
+
+	assertColumn(t, rows, "email", "admin@example.com", "mo@example.com", "steampipe@example.com")
+	assertColumn(t, rows, "global_role", "admin", nil, "observer")
+	assertColumn(t, rows, "api_only", false, false, true)
+	assertRequests(t, fake, "users", 2)
+}
+
+func TestListUsersQuery(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	rows := conn.rows(testQuery{
+		Table:   "fleetdm_user",
+		Columns: []string{"id", "email"},
+		Quals:   map[string]any{"query": "mo@"},
+	})
+
+	assertColumn(t, rows, "id", 2)
+}
@@ -0,0 +1,88 @@
+package fleetdm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+)
+
+// assertPerPage fails the test unless every request to endpoint asked for want items.
+func assertPerPage(t *testing.T, fake *fleettest.Server, endpoint string, want string) {
+	t.Helper()
+	for _, query := range fake.Requests(endpoint) {
+		if got := query.Get("per_page"); got != want {
+			t.Errorf("per_page for %s = %s, want %s", endpoint, got, want)
+		}
+	}
+}
+
+func TestTablePageSize(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, `page_size = 500
+table_page_size = { fleetdm_label = 2 }`)
+
+	conn.rows(testQuery{Table: "fleetdm_label", Columns: []string{"id"}})
+	conn.rows(testQuery{Table: "fleetdm_host", Columns: []string{"id"}})
+	conn.rows(testQuery{Table: "fleetdm_activity", Columns: []string{"id"}})
+
+	assertRequests(t, fake, "labels", 2)
+	assertPerPage(t, fake, "labels", "2")
+	assertPerPage(t, fake, "hosts", "500")
+	// page_size is capped at the largest page Fleet serves for activities
+	assertPerPage(t, fake, "activities", "100")
+}
+
+func TestTablePageSizeAboveMaximum(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, `table_page_size = { fleetdm_activity = 500 }`)
+
+	_, err := conn.execute(testQuery{Table: "fleetdm_host", Columns: []string{"id"}})
+
+	assertErrorContains(t, err, "table_page_size for fleetdm_activity must be at most 100")
+}
+
+func TestTableOptionsRejectUnknownTable(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, `table_request_timeout = { fleetdm_hosts = 60 }`)
+
+	_, err := conn.execute(testQuery{Table: "fleetdm_host", Columns: []string{"id"}})
+
+	assertErrorContains(t, err, "table_request_timeout contains 'fleetdm_hosts', which is not a fleetdm table")
+}
+
+func TestRequestTimeoutRejectsZero(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, `request_timeout = 0`)
+
+	_, err := conn.execute(testQuery{Table: "fleetdm_host", Columns: []string{"id"}})
+
+	assertErrorContains(t, err, "request_timeout must be at least 1 second")
+}
+
+func TestTableRequestTimeout(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	fake.SetDelay("labels", 1500*time.Millisecond)
+	fake.SetDelay("teams", 1500*time.Millisecond)
+	conn := newTestConnection(t, fake, `max_retries = 0
+request_timeout = 5
+table_request_timeout = { fleetdm_label = 1 }`)
+
+	_, err := conn.execute(testQuery{Table: "fleetdm_label", Columns: []string{"id"}})
+	assertErrorContains(t, err, "exceeded allowed timeout")
+
+	// Other tables keep the connection's request_timeout
+	rows := conn.rows(testQuery{Table: "fleetdm_team", Columns: []string{"id"}})
+	if len(rows) == 0 {
+		t.Error("expected teams despite the slow response")
+	}
+}
+
+func TestRateLimitOptionReachesClient(t *testing.T) {
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, `hosts_rate_limit = 0`)
+
+	_, err := conn.execute(testQuery{Table: "fleetdm_host", Columns: []string{"id"}})
+
+	assertErrorContains(t, err, "hosts_rate_limit must be greater than zero")
+}
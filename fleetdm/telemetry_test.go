@@ -0,0 +1,199 @@
+package fleetdm_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleettest"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// The OpenTelemetry providers are global, and instruments created before the
+// first provider is installed are only forwarded to that one, so every test
+// shares these.
+var (
+	telemetryOnce    sync.Once
+	telemetrySpans   *tracetest.SpanRecorder
+	telemetryMetrics *sdkmetric.ManualReader
+)
+
+// telemetryRecording sees the spans and metrics recorded since it was started.
+type telemetryRecording struct {
+	t          *testing.T
+	spanOffset int
+	baseline   metricdata.ResourceMetrics
+}
+
+// recordTelemetry installs in-memory trace and metric providers, in place of the
+// exporters the SDK installs when STEAMPIPE_OTEL_LEVEL is set, and starts a
+// recording. Other tests also record once the providers are installed.
+func recordTelemetry(t *testing.T) *telemetryRecording {
+	t.Helper()
+	telemetryOnce.Do(func() {
+		telemetrySpans = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(telemetrySpans)))
+		telemetryMetrics = sdkmetric.NewManualReader()
+		otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(telemetryMetrics)))
+	})
+	r := &telemetryRecording{t: t, spanOffset: len(telemetrySpans.Ended())}
+	r.baseline = r.collect()
+	return r
+}
+
+func (r *telemetryRecording) collect() metricdata.ResourceMetrics {
+	r.t.Helper()
+	var data metricdata.ResourceMetrics
+	if err := telemetryMetrics.Collect(context.Background(), &data); err != nil {
+		r.t.Fatalf("collecting metrics: %v", err)
+	}
+	return data
+}
+
+// apiSpans returns the API call spans for table ended during the recording, keyed
+// by span name.
+func (r *telemetryRecording) apiSpans(table string) map[string][]sdktrace.ReadOnlySpan {
+	found := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, span := range telemetrySpans.Ended()[r.spanOffset:] {
+		for _, attr := range span.Attributes() {
+			if attr.Key == "fleetdm.table" && attr.Value.AsString() == table {
+				found[span.Name()] = append(found[span.Name()], span)
+			}
+		}
+	}
+	return found
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+// metricSum returns how much a counter grew during the recording, or how many
+// histogram observations were made, across the data points carrying all of the
+// given attributes.
+func (r *telemetryRecording) metricSum(name string, attrs ...attribute.KeyValue) int64 {
+	r.t.Helper()
+	return sumMetric(r.collect(), name, attrs) - sumMetric(r.baseline, name, attrs)
+}
+
+func sumMetric(data metricdata.ResourceMetrics, name string, attrs []attribute.KeyValue) int64 {
+	matches := func(set attribute.Set) bool {
+		for _, attr := range attrs {
+			if value, ok := set.Value(attr.Key); !ok || value != attr.Value {
+				return false
+			}
+		}
+		return true
+	}
+	var total int64
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name != name {
+				continue
+			}
+			switch agg := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, point := range agg.DataPoints {
+					if matches(point.Attributes) {
+						total += point.Value
+					}
+				}
+			case metricdata.Histogram[float64]:
+				for _, point := range agg.DataPoints {
+					if matches(point.Attributes) {
+						total += int64(point.Count)
+					}
+				}
+			}
+		}
+	}
+	return total
+}
+
+func TestTelemetryListPages(t *testing.T) {
+	telemetry := recordTelemetry(t)
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "page_size = 2")
+
+	conn.rows(testQuery{Table: "fleetdm_label", Columns: []string{"id"}})
+
+	calls := telemetry.apiSpans("fleetdm_label")["FleetDMClient.Get (labels)"]
+	if len(calls) != 2 {
+		t.Fatalf("spans for labels = %d, want 2", len(calls))
+	}
+	pages := []any{}
+	for _, span := range calls {
+		pages = append(pages, spanAttribute(span, "fleetdm.page").AsString())
+		if status := spanAttribute(span, "http.status_code").AsInt64(); status != 200 {
+			t.Errorf("http.status_code = %d, want 200", status)
+		}
+	}
+	assertValues(t, "fleetdm.page", pages, "0", "1")
+
+	endpoint := attribute.String("endpoint", "labels")
+	table := attribute.String("table", "fleetdm_label")
+	if got := telemetry.metricSum("fleetdm.api.requests", endpoint, table, attribute.Int("status_code", 200)); got != 2 {
+		t.Errorf("fleetdm.api.requests = %d, want 2", got)
+	}
+	if got := telemetry.metricSum("fleetdm.api.request.duration", endpoint, table); got != 2 {
+		t.Errorf("fleetdm.api.request.duration observations = %d, want 2", got)
+	}
+}
+
+func TestTelemetryHostDetailRoute(t *testing.T) {
+	telemetry := recordTelemetry(t)
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	conn := newTestConnection(t, fake, "")
+
+	conn.rows(testQuery{Table: "fleetdm_host_detail", Columns: []string{"id", "hostname"}, Quals: map[string]any{"id": 3}})
+
+	// Host ids are replaced so each host does not get its own series
+	if calls := telemetry.apiSpans("fleetdm_host_detail")["FleetDMClient.Get (hosts/:id)"]; len(calls) != 1 {
+		t.Errorf("spans for hosts/:id = %d, want 1", len(calls))
+	}
+	if got := telemetry.metricSum("fleetdm.api.requests", attribute.String("endpoint", "hosts/:id"), attribute.String("table", "fleetdm_host_detail")); got != 1 {
+		t.Errorf("fleetdm.api.requests = %d, want 1", got)
+	}
+}
+
+func TestTelemetryErrorsAndRetries(t *testing.T) {
+	telemetry := recordTelemetry(t)
+	fake := fleettest.NewServer(t, fleettest.Config{})
+	fake.InjectError("packs", 503, 1)
+	fake.InjectError("users", 500, 1)
+	conn := newTestConnection(t, fake, "max_retries = 1")
+
+	conn.rows(testQuery{Table: "fleetdm_pack", Columns: []string{"id"}})
+	_, err := conn.execute(testQuery{Table: "fleetdm_user", Columns: []string{"id"}})
+	assertErrorContains(t, err, "500 Internal Server Error")
+
+	if got := telemetry.metricSum("fleetdm.api.retries", attribute.String("endpoint", "packs"), attribute.String("table", "fleetdm_pack")); got != 1 {
+		t.Errorf("fleetdm.api.retries = %d, want 1", got)
+	}
+	if got := telemetry.metricSum("fleetdm.api.requests", attribute.String("endpoint", "users"), attribute.Int("status_code", 500)); got != 1 {
+		t.Errorf("fleetdm.api.requests with status 500 = %d, want 1", got)
+	}
+	calls := telemetry.apiSpans("fleetdm_user")["FleetDMClient.Get (users)"]
+	if len(calls) != 1 || calls[0].Status().Description == "" {
+		t.Errorf("users span = %v, want one span with an error status", calls)
+	}
+}
+
+// assertValues fails the test unless got has exactly the wanted values, in any order.
+func assertValues(t *testing.T, name string, got []any, want ...any) {
+	t.Helper()
+	if !sameValues(got, want) {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
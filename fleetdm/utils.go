@@ -2,57 +2,31 @@ package fleetdm
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"math"
 	"net/http"
-	"net/url"
-	"os" // Added for os.Getenv
-	"strings"
 	"time"
 
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm/fleetapi"
+
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+	"golang.org/x/sync/singleflight"
 )
 
-// FleetTime is a custom time type that handles empty strings in JSON unmarshalling.
-// The Fleet DM API may return empty strings for time fields, which causes
-// the standard time.Time JSON unmarshaller to fail.
-type FleetTime struct {
-	time.Time
-}
-
-// UnmarshalJSON implements the json.Unmarshaler interface for FleetTime.
-func (ft *FleetTime) UnmarshalJSON(data []byte) error {
-	s := string(data)
-	if s == "null" || s == `""` {
-		ft.Time = time.Time{}
-		return nil
-	}
-	return ft.Time.UnmarshalJSON(data)
-}
-
-// MarshalJSON implements the json.Marshaler interface for FleetTime.
-func (ft FleetTime) MarshalJSON() ([]byte, error) {
-	if ft.IsZero() {
-		return []byte("null"), nil
-	}
-	return ft.Time.MarshalJSON()
-}
-
 // flexibleTimeTransform converts FleetTime or time.Time values to time.Time for Steampipe TIMESTAMP columns.
 func flexibleTimeTransform(_ context.Context, d *transform.TransformData) (interface{}, error) {
 	if d.Value == nil {
 		return nil, nil
 	}
 	switch v := d.Value.(type) {
-	case FleetTime:
+	case fleetapi.FleetTime:
 		if v.IsZero() {
 			return nil, nil
 		}
 		return v.Time, nil
-	case *FleetTime:
+	case *fleetapi.FleetTime:
 		if v == nil || v.IsZero() {
 			return nil, nil
 		}
@@ -72,143 +46,263 @@ func flexibleTimeTransform(_ context.Context, d *transform.TransformData) (inter
 	}
 }
 
-// FleetDMClient is a client for the FleetDM API.
-type FleetDMClient struct {
-	BaseURL    string
-	APIToken   string
-	HTTPClient *http.Client
-}
+// clientCacheKey prefixes the connection cache keys under which the shared
+// clients are stored, one per Fleet server.
+const clientCacheKey = "fleetdm_client"
+
+// clientGroup merges concurrent client creation for the same connection and
+// server, so hydrates do not each build a client. Other connections and servers
+// are never held up by a slow api_token_command or /login.
+var clientGroup singleflight.Group
 
-// NewFleetDMClient creates a new FleetDM API client.
-func NewFleetDMClient(ctx context.Context, connection *plugin.Connection) (*FleetDMClient, error) {
-	config := GetConfig(connection) // Gets config from .spc file
+// getClient returns the FleetDM client for the query's connection and the Fleet
+// server the hydrate runs for. The client is created on first use and cached in
+// the connection cache, so all tables and hydrates of a connection share a single
+// HTTP connection pool per server. The SDK clears the connection cache when the
+// connection config changes.
+func getClient(ctx context.Context, d *plugin.QueryData) (*fleetapi.FleetDMClient, error) {
+	server := serverFromContext(ctx)
+	cacheKey := clientCacheKey + ":" + server
+	if cached, ok := d.ConnectionCache.Get(ctx, cacheKey); ok {
+		return cached.(*fleetapi.FleetDMClient), nil
+	}
 
-	serverURL := ""
-	apiToken := ""
+	client, err, _ := clientGroup.Do(d.Connection.Name+"/"+cacheKey, func() (interface{}, error) {
+		// Another hydrate may have created the client since the cache was checked
+		if cached, ok := d.ConnectionCache.Get(ctx, cacheKey); ok {
+			return cached.(*fleetapi.FleetDMClient), nil
+		}
 
-	// Get Server URL: .spc file takes precedence, then environment variable
-	if config.ServerURL != nil && *config.ServerURL != "" {
-		serverURL = *config.ServerURL
-		plugin.Logger(ctx).Info("NewFleetDMClient", "server_url_source", ".spc_file")
-	} else {
-		envURL := os.Getenv("FLEETDM_URL")
-		if envURL != "" {
-			serverURL = envURL
-			plugin.Logger(ctx).Info("NewFleetDMClient", "server_url_source", "env_FLEETDM_URL")
+		if err := checkTableOptions(GetConfig(d.Connection), d.Table.Plugin.TableMap); err != nil {
+			return nil, err
+		}
+		if err := checkPageSizes(GetConfig(d.Connection)); err != nil {
+			return nil, err
+		}
+		client, err := newServerClient(ctx, d.Connection, server)
+		if err != nil {
+			return nil, err
 		}
-	}
 
-	// Get API Token: .spc file takes precedence, then environment variable
-	if config.APIToken != nil && *config.APIToken != "" {
-		apiToken = *config.APIToken
-		plugin.Logger(ctx).Info("NewFleetDMClient", "api_token_source", ".spc_file")
-	} else {
-		envToken := os.Getenv("FLEETDM_API_TOKEN")
-		if envToken != "" {
-			apiToken = envToken
-			plugin.Logger(ctx).Info("NewFleetDMClient", "api_token_source", "env_FLEETDM_API_TOKEN")
+		// A zero TTL keeps the client until the connection cache is cleared
+		if err := d.ConnectionCache.SetWithTTL(ctx, cacheKey, client, 0); err != nil {
+			plugin.Logger(ctx).Warn("getClient", "connection_cache_set_error", err, "connection", d.Connection.Name, "server", server)
 		}
+		return client, nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return client.(*fleetapi.FleetDMClient), nil
+}
 
-	// Validate that we have the necessary configuration
-	if serverURL == "" {
-		return nil, errors.New("server_url must be configured in fleetdm.spc or via FLEETDM_URL environment variable")
+// newServerClient creates the client for the named server of a connection.
+func newServerClient(ctx context.Context, connection *plugin.Connection, name string) (*fleetapi.FleetDMClient, error) {
+	servers, err := connectionServers(connection)
+	if err != nil {
+		return nil, err
+	}
+	config := GetConfig(connection)
+	if len(config.Servers) == 0 {
+		return fleetapi.NewClient(ctx, clientConfig(config))
 	}
-	if apiToken == "" {
-		return nil, errors.New("api_token must be configured in fleetdm.spc or via FLEETDM_API_TOKEN environment variable")
+	for _, server := range servers {
+		if server.Name == name {
+			plugin.Logger(ctx).Info("newServerClient", "server", name)
+			return fleetapi.NewClient(ctx, clientConfig(serverConfig(config, server)))
+		}
 	}
+	return nil, fmt.Errorf("server '%s' is not configured in servers", name)
+}
 
-	// Normalize the baseURL
-	baseURL := strings.TrimSuffix(serverURL, "/")
-	if strings.HasSuffix(baseURL, "/api/v1/fleet") {
-		baseURL += "/"
-	} else if strings.HasSuffix(baseURL, "/api/v1") {
-		baseURL += "/fleet/"
-	} else if strings.HasSuffix(baseURL, "/api") {
-		baseURL += "/v1/fleet/"
-	} else {
-		baseURL += "/api/v1/fleet/"
+// clientConfig returns the client options set in the connection config. The
+// page size options are applied by the tables, see listOptions.
+func clientConfig(config fleetdmConfig) fleetapi.Config {
+	return fleetapi.Config{
+		ServerURL:                config.ServerURL,
+		APIToken:                 config.APIToken,
+		APITokenFile:             config.APITokenFile,
+		APITokenCommand:          config.APITokenCommand,
+		Email:                    config.Email,
+		Password:                 config.Password,
+		MaxRetries:               config.MaxRetries,
+		MaxRetryBackoff:          config.MaxRetryBackoff,
+		RequestTimeout:           config.RequestTimeout,
+		LabelRequestTimeout:      config.TableRequestTimeout,
+		MaxIdleConnsPerHost:      config.MaxIdleConnsPerHost,
+		IdleConnTimeout:          config.IdleConnTimeout,
+		TCPKeepAlive:             config.TCPKeepAlive,
+		CACertFile:               config.CACertFile,
+		ClientCertFile:           config.ClientCertFile,
+		ClientKeyFile:            config.ClientKeyFile,
+		TLSServerName:            config.TLSServerName,
+		InsecureSkipVerify:       config.InsecureSkipVerify,
+		ProxyURL:                 config.ProxyURL,
+		Headers:                  config.Headers,
+		RecordDir:                config.RecordDir,
+		ReplayDir:                config.ReplayDir,
+		HostsRateLimit:           config.HostsRateLimit,
+		HostDetailRateLimit:      config.HostDetailRateLimit,
+		HostDetailMaxConcurrency: config.HostDetailMaxConcurrency,
+		SoftwareRateLimit:        config.SoftwareRateLimit,
+		ActivitiesRateLimit:      config.ActivitiesRateLimit,
+		CacheDir:                 config.CacheDir,
+		HostsCacheTTL:            config.HostsCacheTTL,
+		HostDetailCacheTTL:       config.HostDetailCacheTTL,
+		SoftwareCacheTTL:         config.SoftwareCacheTTL,
+		ActivitiesCacheTTL:       config.ActivitiesCacheTTL,
+		DebugLogBodies:           config.DebugLogBodies,
+		Logger:                   plugin.Logger,
 	}
-	
-	plugin.Logger(ctx).Debug("NewFleetDMClient", "final_derived_base_url", baseURL)
+}
 
+// maxPageSizes are the largest per_page values Fleet documents for the list
+// endpoints behind each table. Tables missing here have no documented maximum.
+var maxPageSizes = map[string]int{
+	"fleetdm_activity": 100,
+	"fleetdm_label":    100,
+	"fleetdm_pack":     100,
+	"fleetdm_query":    100,
+}
 
-	return &FleetDMClient{
-		BaseURL:  baseURL,
-		APIToken: apiToken,
-		HTTPClient: &http.Client{
-			Timeout: 30 * time.Second, 
-		},
-	}, nil
+// checkPageSizes checks the page_size and table_page_size options, the latter
+// against Fleet's maximums.
+func checkPageSizes(config fleetdmConfig) error {
+	if config.PageSize != nil && *config.PageSize < 1 {
+		return errors.New("page_size must be at least 1")
+	}
+	for table, size := range config.TablePageSize {
+		if size < 1 {
+			return fmt.Errorf("table_page_size for %s must be at least 1", table)
+		}
+		if limit := maxPageSizes[table]; limit > 0 && size > limit {
+			return fmt.Errorf("table_page_size for %s must be at most %d, the largest page Fleet serves for it", table, limit)
+		}
+	}
+	return nil
 }
 
-// Get performs a GET request to the specified FleetDM API endpoint.
-// The response is unmarshalled into the `target` interface.
-func (c *FleetDMClient) Get(ctx context.Context, endpoint string, queryParams url.Values, target interface{}) (*http.Response, error) {
-	// Construct the full URL
-	// Ensure endpoint doesn't start with a slash if BaseURL already ends with one
-	trimmedEndpoint := strings.TrimPrefix(endpoint, "/")
-	fullURLString := c.BaseURL + trimmedEndpoint
-	
-	fullURL, err := url.Parse(fullURLString)
-	if err != nil {
-		plugin.Logger(ctx).Error("FleetDMClient.Get", "url_parse_error", err, "base_url", c.BaseURL, "endpoint", endpoint)
-		return nil, fmt.Errorf("error parsing base URL '%s' and endpoint '%s': %w", c.BaseURL, endpoint, err)
+// checkTableOptions rejects table_page_size and table_request_timeout entries
+// which do not name one of the plugin's tables, and timeouts below one second.
+func checkTableOptions(config fleetdmConfig, tables map[string]*plugin.Table) error {
+	for option, overrides := range map[string]map[string]int{
+		"table_page_size":       config.TablePageSize,
+		"table_request_timeout": config.TableRequestTimeout,
+	} {
+		for table := range overrides {
+			if _, ok := tables[table]; !ok {
+				return fmt.Errorf("%s contains '%s', which is not a fleetdm table", option, table)
+			}
+		}
 	}
-	if queryParams != nil {
-		fullURL.RawQuery = queryParams.Encode()
+	for table, timeout := range config.TableRequestTimeout {
+		if timeout < 1 {
+			return fmt.Errorf("table_request_timeout for %s must be at least 1 second", table)
+		}
 	}
+	return nil
+}
 
-	plugin.Logger(ctx).Debug("FleetDMClient.Get", "url", fullURL.String())
+// pageSize returns the per_page value for a table's endpoint: the table_page_size
+// option for the table, otherwise the page_size option, otherwise zero for the
+// endpoint's default. page_size is capped at the table's maximum, so it can be
+// set above the maximum of some tables.
+func pageSize(config fleetdmConfig, table string) int {
+	if size, ok := config.TablePageSize[table]; ok {
+		return size
+	}
+	if config.PageSize != nil {
+		if limit := maxPageSizes[table]; limit > 0 && *config.PageSize > limit {
+			return limit
+		}
+		return *config.PageSize
+	}
+	return 0
+}
 
-	// Create the request
-	req, err := http.NewRequestWithContext(ctx, "GET", fullURL.String(), nil)
-	if err != nil {
-		plugin.Logger(ctx).Error("FleetDMClient.Get", "request_creation_error", err, "url", fullURL.String())
-		return nil, fmt.Errorf("error creating HTTP request for %s: %w", fullURL.String(), err)
+// listOptions returns the paging options for listing a table's rows: the page
+// size options, the query's limit, so a small limit requests a small page, and
+// the SDK's list rate limiter, waited on before every page.
+func listOptions(ctx context.Context, d *plugin.QueryData) fleetapi.ListOptions {
+	opts := fleetapi.ListOptions{
+		PerPage:    pageSize(GetConfig(d.Connection), d.Table.Name),
+		BeforePage: d.WaitForListRateLimit,
+	}
+	if remaining := d.RowsRemaining(ctx); remaining > 0 && remaining < math.MaxInt32 {
+		opts.Limit = int(remaining)
 	}
+	return opts
+}
 
-	// Set headers
-	req.Header.Set("Authorization", "Bearer "+c.APIToken)
-	req.Header.Set("Accept", "application/json")
+// streamItems returns a List callback which streams each item to Steampipe and
+// stops the listing as soon as the query's limit is reached.
+func streamItems[T any](ctx context.Context, d *plugin.QueryData) func(item T) bool {
+	return func(item T) bool {
+		d.StreamListItem(ctx, item)
+		return d.RowsRemaining(ctx) != 0
+	}
+}
 
-	// Perform the request
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		plugin.Logger(ctx).Error("FleetDMClient.Get", "http_do_error", err, "url", fullURL.String())
-		return resp, fmt.Errorf("error performing HTTP request to %s: %w", fullURL.String(), err)
+// qualString returns the value of a string key column, or "" if the query does
+// not set it.
+func qualString(d *plugin.QueryData, column string) string {
+	if d.EqualsQuals[column] == nil {
+		return ""
 	}
-	defer func() {
-		if cerr := resp.Body.Close(); cerr != nil {
-			plugin.Logger(ctx).Error("FleetDMClient.Get", "close_error", cerr, "url", fullURL.String())
-		}
-	}()
-
-	// Check for non-2xx status codes
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		bodyBytes, readErr := io.ReadAll(resp.Body)
-		if readErr != nil {
-			plugin.Logger(ctx).Error("FleetDMClient.Get", "read_error_body_failed", readErr, "url", fullURL.String(), "status_code", resp.StatusCode)
-			return resp, fmt.Errorf("API request to %s failed with status %s (unable to read error body)", fullURL.String(), resp.Status)
-		}
-		plugin.Logger(ctx).Error("FleetDMClient.Get", "api_error_response", string(bodyBytes), "url", fullURL.String(), "status_code", resp.StatusCode)
-		return resp, fmt.Errorf("API request to %s failed with status %s: %s", fullURL.String(), resp.Status, string(bodyBytes))
+	return d.EqualsQuals[column].GetStringValue()
+}
+
+// qualUint returns the value of an INT key column holding an id, or nil if the
+// query does not set it.
+func qualUint(d *plugin.QueryData, column string) *uint {
+	if d.EqualsQuals[column] == nil {
+		return nil
 	}
+	value := uint(d.EqualsQuals[column].GetInt64Value())
+	return &value
+}
 
-	// Decode the JSON response
-	if target != nil {
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			plugin.Logger(ctx).Error("FleetDMClient.Get", "read_body_for_decode_error", err, "url", fullURL.String())
-			return resp, fmt.Errorf("error reading response body from %s: %w", fullURL.String(), err)
-		}
+// qualInt returns the value of an INT key column, or nil if the query does not set it.
+func qualInt(d *plugin.QueryData, column string) *int {
+	if d.EqualsQuals[column] == nil {
+		return nil
+	}
+	value := int(d.EqualsQuals[column].GetInt64Value())
+	return &value
+}
+
+// qualBool returns the value of a BOOL key column, or nil if the query does not set it.
+func qualBool(d *plugin.QueryData, column string) *bool {
+	if d.EqualsQuals[column] == nil {
+		return nil
+	}
+	value := d.EqualsQuals[column].GetBoolValue()
+	return &value
+}
 
+// tableContext returns ctx labelled with the query's table, which is added to the
+// spans and metrics of the API calls made with it and selects the table's
+// table_request_timeout.
+func tableContext(ctx context.Context, d *plugin.QueryData) context.Context {
+	if d == nil || d.Table == nil {
+		return ctx
+	}
+	return fleetapi.WithLabel(ctx, d.Table.Name)
+}
 
-		if err := json.Unmarshal(bodyBytes, target); err != nil {
-			plugin.Logger(ctx).Error("FleetDMClient.Get", "json_decode_error", err, "url", fullURL.String(), "response_body_snippet", string(bodyBytes[:500])) // Log a snippet
-			return resp, fmt.Errorf("error decoding JSON response from %s: %w. Response body: %s", fullURL.String(), err, string(bodyBytes))
-		}
+// cacheBypassContext returns ctx marked to refresh the response cache when the
+// query sets the cache_bypass qual.
+func cacheBypassContext(ctx context.Context, d *plugin.QueryData) context.Context {
+	if d.EqualsQuals["cache_bypass"] != nil && d.EqualsQuals["cache_bypass"].GetBoolValue() {
+		plugin.Logger(ctx).Debug("cacheBypassContext", "cache_bypass", true, "table", d.Table.Name)
+		return fleetapi.WithCacheRefresh(ctx)
 	}
+	return ctx
+}
 
-	return resp, nil
-}
\ No newline at end of file
+// isNotFoundError is an ErrorPredicateWithContext which matches 404 responses, so
+// Get hydrates return zero rows for ids that do not exist instead of failing.
+func isNotFoundError(_ context.Context, _ *plugin.QueryData, _ *plugin.HydrateData, err error) bool {
+	var apiErr *fleetapi.FleetAPIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
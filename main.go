@@ -2,7 +2,7 @@ package main
 
 import (
 
-	"steampipe-plugin-fleetdm/fleetdm"
+	"github.com/l-teles/steampipe-plugin-fleetdm/fleetdm"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 
 